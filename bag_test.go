@@ -0,0 +1,383 @@
+//  Copyright (c) 2013 Couchbase, Inc.
+
+package monster
+
+import "testing"
+import "bytes"
+import "compress/gzip"
+import "fmt"
+import "io/ioutil"
+import "os"
+import "net/http"
+import "net/http/httptest"
+import "path/filepath"
+import "strings"
+import "time"
+
+import "github.com/prataprc/goparsec"
+import "github.com/prataprc/monster/builtin"
+import "github.com/prataprc/monster/common"
+
+var _ = fmt.Sprintf("dummy")
+
+func TestBagTTL(t *testing.T) {
+	dir, err := ioutil.TempDir("", "monster")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "ttlbag")
+	if err := ioutil.WriteFile(filename, []byte("old\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	builtin.SetBagTTL(100 * time.Millisecond)
+	defer builtin.SetBagTTL(0)
+	scope := compileText(t, ``, 1800)
+	if val := builtin.Bag(scope, filename); val != "old" {
+		t.Fatalf("expected old, got %v", val)
+	}
+	if err := ioutil.WriteFile(filename, []byte("new\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	future := time.Now().Add(time.Hour)
+	if err := os.Chtimes(filename, future, future); err != nil {
+		t.Fatal(err)
+	}
+	if val := builtin.Bag(scope, filename); val != "old" {
+		t.Fatalf("expected cached old before ttl, got %v", val)
+	}
+	time.Sleep(150 * time.Millisecond)
+	if val := builtin.Bag(scope, filename); val != "new" {
+		t.Fatalf("expected reloaded new after ttl, got %v", val)
+	}
+}
+
+func TestBagCacheContext(t *testing.T) {
+	dirs := make([]string, 2)
+	for i := range dirs {
+		dir, err := ioutil.TempDir("", "monster")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+		data := []byte(fmt.Sprintf("data%v\n", i))
+		if err := ioutil.WriteFile(filepath.Join(dir, "same"), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+		dirs[i] = dir
+	}
+	build := func(bagdir string) common.Scope {
+		root, _ := Y(parsec.NewScanner([]byte(``)))
+		return BuildContext(root.(common.Scope), 1900, bagdir, "")
+	}
+	scope0, scope1 := build(dirs[0]), build(dirs[1])
+	for i := 0; i < 2; i++ {
+		if val := builtin.Bag(scope0, "same"); val != "data0" {
+			t.Fatalf("expected data0, got %v", val)
+		} else if val := builtin.Bag(scope1, "same"); val != "data1" {
+			t.Fatalf("expected data1, got %v", val)
+		}
+	}
+
+	// a new context reads the bag afresh, while the older context
+	// continues with its cached copy.
+	filename := filepath.Join(dirs[0], "same")
+	if err := ioutil.WriteFile(filename, []byte("data2\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if val := builtin.Bag(build(dirs[0]), "same"); val != "data2" {
+		t.Fatalf("expected data2 in new context, got %v", val)
+	} else if val := builtin.Bag(scope0.RebuildContext(), "same"); val != "data0" {
+		t.Fatalf("expected cached data0 in old context, got %v", val)
+	}
+}
+
+func TestBagCacheLRU(t *testing.T) {
+	dir, err := ioutil.TempDir("", "monster")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	write := func(name, data string) {
+		filename := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(filename, []byte(data+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, name := range []string{"a", "b", "c"} {
+		write(name, "old"+name)
+	}
+
+	builtin.SetBagCacheSize(2)
+	defer builtin.SetBagCacheSize(builtin.DefaultBagCacheSize)
+	root, _ := Y(parsec.NewScanner([]byte(``)))
+	scope := BuildContext(root.(common.Scope), 2000, dir, "")
+	for _, name := range []string{"a", "b", "a", "c"} { // evicts "b"
+		if val := builtin.Bag(scope, name); val != "old"+name {
+			t.Fatalf("expected old%v, got %v", name, val)
+		}
+	}
+	write("a", "newa")
+	write("b", "newb")
+	if val := builtin.Bag(scope, "a"); val != "olda" {
+		t.Fatalf("expected cached olda, got %v", val)
+	} else if val := builtin.Bag(scope, "b"); val != "newb" {
+		t.Fatalf("expected evicted bag to be reloaded, got %v", val)
+	} else if val := builtin.Bag(scope, "c"); val != "oldc" {
+		t.Fatalf("expected cached oldc, got %v", val)
+	} else if val := builtin.Bag(scope, "a"); val != "newa" {
+		t.Fatalf("expected least recently used olda to be evicted, got %v", val)
+	}
+}
+
+func TestBagGzip(t *testing.T) {
+	scope := compileText(t, ``, 2100)
+	for idx := int64(0); idx < 6; idx++ {
+		for col := int64(0); col < 3; col++ {
+			val := builtin.Bagn(scope, "people.csv", col, idx)
+			if gz := builtin.Bagn(scope, "people.csv.gz", col, idx); gz != val {
+				t.Fatalf("expected %v from people.csv.gz, got %v", val, gz)
+			} else if gz := builtin.Bagn(scope, "peoplegz", col, idx); gz != val {
+				t.Fatalf("expected %v from gzip magic, got %v", val, gz)
+			}
+		}
+	}
+}
+
+func TestBagIndex(t *testing.T) {
+	dir, err := ioutil.TempDir("", "monster")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "kv")
+	if err := ioutil.WriteFile(filename, []byte("a,1\nb,2\na,3\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	root, _ := Y(parsec.NewScanner([]byte(``)))
+	scope := BuildContext(root.(common.Scope), 2200, dir, "")
+	lookup := func() map[string]bool {
+		seen := make(map[string]bool)
+		for i := 0; i < 50; i++ {
+			seen[builtin.Bagwhere(scope, "kv", int64(1), int64(0), "a").(string)] = true
+			seen[builtin.Condbag(scope, "kv", int64(0), int64(1), "a").(string)] = true
+		}
+		return seen
+	}
+	if seen := lookup(); len(seen) != 2 || !seen["1"] || !seen["3"] {
+		t.Fatalf("expected 1 and 3, got %v", seen)
+	}
+	if err := ioutil.WriteFile(filename, []byte("a,5\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	builtin.InvalidateBagIndex(scope, "kv")
+	if seen := lookup(); len(seen) != 2 || !seen["1"] || !seen["3"] {
+		t.Fatalf("expected cached records after invalidating index, got %v", seen)
+	}
+	builtin.InvalidateBag(scope, "kv")
+	if seen := lookup(); len(seen) != 1 || !seen["5"] {
+		t.Fatalf("expected reloaded records after invalidating bag, got %v", seen)
+	}
+}
+
+func TestBagDelimiter(t *testing.T) {
+	testcases := []struct {
+		delim    rune
+		filename string
+		rows     map[string]bool
+	}{
+		{'\t', "people.tsv", map[string]bool{
+			"name;age;city": true, "alice;31;Paris": true, "bob;42;London": true,
+			"carol;27;Berlin": true, "dave;35;Madrid": true, "eve;29;Rome": true,
+		}},
+		{'|', "people.psv", map[string]bool{
+			"name;age;city": true, "alice;31;Paris, France": true,
+			"bob;42;London": true,
+		}},
+	}
+	defer builtin.SetBagDelimiter(',')
+	for _, tcase := range testcases {
+		builtin.SetBagDelimiter(tcase.delim)
+		scope := compileText(t, ``, 2300)
+		for idx := int64(0); idx < int64(len(tcase.rows)); idx++ {
+			fields := make([]string, 0, 3)
+			for col := int64(0); col < 3; col++ {
+				fields = append(fields, builtin.Bagn(scope, tcase.filename, col, idx).(string))
+			}
+			if row := strings.Join(fields, ";"); !tcase.rows[row] {
+				t.Fatalf("unexpected row %q in %v", row, tcase.filename)
+			}
+		}
+	}
+}
+
+func TestBagHTTP(t *testing.T) {
+	hits := 0
+	srv := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			if hits++; r.URL.Path == "/flaky.csv" && hits == 1 {
+				http.Error(w, "try again", http.StatusServiceUnavailable)
+				return
+			}
+			fmt.Fprintf(w, "red,1\ngreen,2\nblue,3\n")
+		}))
+	defer srv.Close()
+
+	colors := map[string]bool{"red": true, "green": true, "blue": true}
+	scope := compileText(t, ``, 2400)
+	seen := make(map[string]bool)
+	for i := 0; i < 50; i++ {
+		val := builtin.Bag(scope, srv.URL+"/colors.csv").(string)
+		if !colors[val] {
+			t.Fatalf("unexpected %v", val)
+		}
+		seen[val] = true
+	}
+	if len(seen) != len(colors) {
+		t.Fatalf("expected all colors, got %v", seen)
+	} else if hits != 1 {
+		t.Fatalf("expected bag to be fetched once, got %v", hits)
+	}
+
+	hits = 0
+	if val := builtin.Bagn(scope, srv.URL+"/flaky.csv", int64(1), int64(2)); val != "3" {
+		t.Fatalf("expected 3 after retry, got %v", val)
+	} else if hits != 2 {
+		t.Fatalf("expected one retry, got %v hits", hits)
+	}
+}
+
+func TestBagHTTPSlow(t *testing.T) {
+	started, release := make(chan bool, 1), make(chan bool)
+	srv := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			started <- true
+			<-release
+			fmt.Fprintf(w, "slow\n")
+		}))
+	defer srv.Close()
+	defer close(release)
+
+	done := make(chan interface{}, 1)
+	go func() {
+		scope := compileText(t, ``, 2500)
+		done <- builtin.Bag(scope, srv.URL+"/slow.csv")
+	}()
+	<-started
+	// while the URL is being fetched, other bags are not blocked.
+	lookup := make(chan interface{}, 1)
+	go func() {
+		scope := compileText(t, ``, 2500)
+		lookup <- builtin.Bagn(scope, "products.csv", int64(1), int64(0))
+	}()
+	select {
+	case val := <-lookup:
+		if val != "widget" {
+			t.Fatalf("expected widget, got %v", val)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("local bag lookup blocked by slow URL")
+	}
+	release <- true
+	if val := <-done; val != "slow" {
+		t.Fatalf("expected slow, got %v", val)
+	}
+}
+
+func TestBagJSON(t *testing.T) {
+	dir, err := ioutil.TempDir("", "monster")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write([]byte(`["gz"]`))
+	zw.Close()
+	if err := ioutil.WriteFile(filepath.Join(dir, "a.json.gz"), buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	filename := filepath.Join(dir, "b.json")
+	if err := ioutil.WriteFile(filename, []byte(`["old"]`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintf(w, `{"url": 1}`)
+		}))
+	defer srv.Close()
+
+	root, _ := Y(parsec.NewScanner([]byte(``)))
+	scope := BuildContext(root.(common.Scope), 2600, dir, "")
+	if val := builtin.Jbag(scope, "a.json.gz"); val != "gz" {
+		t.Fatalf("expected gz, got %v", val)
+	} else if val := builtin.Jbagkey(scope, srv.URL+"/c.json"); val != "url" {
+		t.Fatalf("expected url, got %v", val)
+	} else if val := builtin.Jbag(scope, "b.json"); val != "old" {
+		t.Fatalf("expected old, got %v", val)
+	}
+	if err := ioutil.WriteFile(filename, []byte(`["new"]`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if val := builtin.Jbag(scope, "b.json"); val != "old" {
+		t.Fatalf("expected cached old, got %v", val)
+	}
+	builtin.InvalidateBag(scope, "b.json")
+	if val := builtin.Jbag(scope, "b.json"); val != "new" {
+		t.Fatalf("expected new after invalidating bag, got %v", val)
+	}
+}
+
+func TestBagDerived(t *testing.T) {
+	dir, err := ioutil.TempDir("", "monster")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	future := time.Now()
+	write := func(name, data string) {
+		filename := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(filename, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+		future = future.Add(time.Hour)
+		if err := os.Chtimes(filename, future, future); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("weights", "old,1\n")
+	write("left", "id,name\n1,alice\n")
+	write("right", "id,city\n1,paris\n")
+
+	builtin.SetBagTTL(time.Millisecond)
+	defer builtin.SetBagTTL(0)
+	root, _ := Y(parsec.NewScanner([]byte(``)))
+	scope := BuildContext(root.(common.Scope), 2700, dir, "")
+	join := func() string {
+		return builtin.Bagjoin(scope, "left", "id", "right", "id").(string)
+	}
+	if val := builtin.Wbagfast(scope, "weights", int64(0), int64(1)); val != "old" {
+		t.Fatalf("expected old, got %v", val)
+	} else if val := join(); !strings.Contains(val, "paris") {
+		t.Fatalf("expected paris, got %v", val)
+	}
+	write("weights", "new,1\n")
+	write("right", "id,city\n1,rome\n")
+	time.Sleep(5 * time.Millisecond)
+	if val := builtin.Wbagfast(scope, "weights", int64(0), int64(1)); val != "new" {
+		t.Fatalf("expected new after reload, got %v", val)
+	} else if val := join(); !strings.Contains(val, "rome") {
+		t.Fatalf("expected rome after reloading right bag, got %v", val)
+	}
+
+	builtin.SetBagTTL(0)
+	builtin.SetBagCacheSize(1)
+	defer builtin.SetBagCacheSize(builtin.DefaultBagCacheSize)
+	write("weights", "evicted,1\n")
+	write("other", "other\n")
+	builtin.Bag(scope, "other") // evicts weights, along with its alias table.
+	if val := builtin.Wbagfast(scope, "weights", int64(0), int64(1)); val != "evicted" {
+		t.Fatalf("expected evicted after eviction, got %v", val)
+	}
+}
@@ -0,0 +1,38 @@
+//  Copyright (c) 2013 Couchbase, Inc.
+
+package builtin
+
+import "fmt"
+import "math"
+
+import "github.com/prataprc/monster/common"
+
+// Annealchoice will pick one of the values, starting with a skewed
+// distribution where each value is half as likely as the previous
+// one, and linearly annealing to uniform distribution as `idx`
+// moves from 0 to `total`. Beyond `total` values are picked
+// uniformly.
+// args[0] - idx, integer or name of an integer variable.
+// args[1] - total, number of records over which to anneal.
+// args[2:] - values to choose from.
+func Annealchoice(scope common.Scope, args ...interface{}) interface{} {
+	if len(args) < 3 {
+		panic(fmt.Errorf("annealchoice expects idxvar, total and values\n"))
+	}
+	idx, total := varInt64(scope, args[0]), toInt64(args[1])
+	if total <= 0 {
+		panic(fmt.Errorf("annealchoice total %v must be positive\n", total))
+	}
+	frac := math.Min(math.Max(float64(idx)/float64(total), 0), 1)
+	values := args[2:]
+	skewed, norm := make([]float64, len(values)), 0.0
+	for i := range skewed {
+		skewed[i] = math.Pow(0.5, float64(i))
+		norm += skewed[i]
+	}
+	weights := make([]float64, len(values))
+	for i := range weights {
+		weights[i] = (1-frac)*skewed[i]/norm + frac/float64(len(values))
+	}
+	return values[pickWeighted(scope.GetRandom(), weights)]
+}
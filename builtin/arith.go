@@ -0,0 +1,85 @@
+//  Copyright (c) 2013 Couchbase, Inc.
+
+package builtin
+
+import "fmt"
+
+import "github.com/prataprc/monster/common"
+
+// Add will return the sum of its arguments. If any of the arguments
+// is float64 the result is float64, else int64.
+func Add(scope common.Scope, args ...interface{}) interface{} {
+	return arith("add", args,
+		func(a, b int64) int64 { return a + b },
+		func(a, b float64) float64 { return a + b })
+}
+
+// Sub will subtract args[1:] from args[0], left to right. If any of
+// the arguments is float64 the result is float64, else int64.
+func Sub(scope common.Scope, args ...interface{}) interface{} {
+	return arith("sub", args,
+		func(a, b int64) int64 { return a - b },
+		func(a, b float64) float64 { return a - b })
+}
+
+// Mul will return the product of its arguments. If any of the
+// arguments is float64 the result is float64, else int64.
+func Mul(scope common.Scope, args ...interface{}) interface{} {
+	return arith("mul", args,
+		func(a, b int64) int64 { return a * b },
+		func(a, b float64) float64 { return a * b })
+}
+
+// Div will divide args[0] by args[1:], left to right. If any of the
+// arguments is float64 the result is float64, else int64 using
+// integer division.
+func Div(scope common.Scope, args ...interface{}) interface{} {
+	for i := 1; i < len(args); i++ {
+		if toFloat64(args[i]) == 0 {
+			panic(fmt.Errorf("div by zero in %v\n", args))
+		}
+	}
+	return arith("div", args,
+		func(a, b int64) int64 { return a / b },
+		func(a, b float64) float64 { return a / b })
+}
+
+// arith will fold `args` left to right, using `intop` if all of
+// them are int64, else using `floatop`.
+func arith(
+	name string, args []interface{},
+	intop func(a, b int64) int64,
+	floatop func(a, b float64) float64) interface{} {
+
+	if len(args) < 2 {
+		panic(fmt.Errorf("%v expects atleast two arguments\n", name))
+	}
+	if isInts(args) {
+		acc := args[0].(int64)
+		for _, arg := range args[1:] {
+			acc = intop(acc, arg.(int64))
+		}
+		return acc
+	}
+	acc := toFloat64(args[0])
+	for _, arg := range args[1:] {
+		acc = floatop(acc, toFloat64(arg))
+	}
+	return acc
+}
+
+// isInts will return true if all the arguments are int64, and panic
+// if any of them is not a number.
+func isInts(args []interface{}) bool {
+	ints := true
+	for _, arg := range args {
+		switch arg.(type) {
+		case int64:
+		case float64:
+			ints = false
+		default:
+			panic(fmt.Errorf("expected number, got %T(%v)\n", arg, arg))
+		}
+	}
+	return ints
+}
@@ -4,22 +4,129 @@ package builtin
 
 import "fmt"
 import "os"
+import "io"
+import "io/ioutil"
+import "bufio"
+import "bytes"
+import "strings"
+import "compress/gzip"
+import "container/list"
 import "encoding/csv"
 import "path/filepath"
 import "sync"
+import "time"
 
 import "github.com/prataprc/monster/common"
 
-var cacheBagRecords = make(map[string][][]string)
+// bagEntry is a cached bag along with the modification time of its
+// file and the last time it was checked for staleness. Reverse
+// indexes, from a key column's value to record indices, are built
+// lazily for conditional lookups. Raw bags, like JSON files, are kept
+// as is instead of being parsed into records. Structures derived from
+// the bag are cached along with it, and dropped when the bag is
+// evicted or reloaded.
+type bagEntry struct {
+	key      string // key into bagCache.records, refer bagKey.
+	filename string
+	raw      bool
+	records  [][]string
+	data     []byte                     // content of raw bag.
+	indexes  map[int64]map[string][]int // keycol -> key -> []rowidx
+	mtime    time.Time
+	checked  time.Time
+	elem     *list.Element // position in bagCache.lru
+	derived  map[string]*derivedEntry
+}
+
+// derivedEntry is a structure derived from one or more bags.
+type derivedEntry struct {
+	val  interface{}
+	deps []*bagEntry
+}
+
+// bagCache holds bags read while evaluating a context. It is kept in
+// the global scope so that each context built by BuildContext has its
+// own bag state.
+type bagCache struct {
+	records map[string]*bagEntry
+	lru     *list.List // most recently used bag in front.
+}
+
+// DefaultBagCacheSize is the default number of bags cached for each
+// context.
+const DefaultBagCacheSize = 128
+
+// bagrw guards bag caches across all contexts.
 var bagrw sync.RWMutex
+var bagTTL time.Duration
+var bagCacheSize = DefaultBagCacheSize
+var bagDelimiter = ','
+
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// NewBagCache will attach an empty bag cache to scope, replacing
+// any existing cache. Bags are read afresh for the new cache.
+func NewBagCache(scope common.Scope) common.Scope {
+	cache := &bagCache{
+		records: make(map[string]*bagEntry),
+		lru:     list.New(),
+	}
+	return scope.Set("_bagcache", cache, true /*global*/)
+}
+
+// SetBagTTL will make cached bags older than `ttl` to be re-checked
+// against their file's modification time, and reloaded if the file
+// has changed. A `ttl` of zero, which is the default, disables the
+// check.
+func SetBagTTL(ttl time.Duration) {
+	bagrw.Lock()
+	defer bagrw.Unlock()
+	bagTTL = ttl
+}
+
+// SetBagCacheSize will limit the number of bags cached for each
+// context to `n`, least recently used bags are evicted beyond that
+// and read again on their next use.
+func SetBagCacheSize(n int) {
+	if n < 1 {
+		panic(fmt.Errorf("bag cache size %v must be positive\n", n))
+	}
+	bagrw.Lock()
+	defer bagrw.Unlock()
+	bagCacheSize = n
+}
+
+// SetBagDelimiter will set the field delimiter for bag files, like
+// '\t' or '|', defaults to ','. Applies to bags read after the call.
+func SetBagDelimiter(r rune) {
+	bagrw.Lock()
+	defer bagrw.Unlock()
+	bagDelimiter = r
+}
 
 // Bag will fetch a random line from file and return it.
 // args[0] - filename.
 func Bag(scope common.Scope, args ...interface{}) interface{} {
+	records := bagRecords(scope, args[0].(string))
+	if len(records) > 0 {
+		rnd := scope.GetRandom()
+		record := records[rnd.Intn(len(records))]
+		if len(record) > 0 {
+			return record[0]
+		}
+	}
+	return ""
+}
+
+// bagPath will resolve `filename` relative to the bag-dir, or
+// relative to the production file, and return its absolute path.
+// http and https URLs are returned as is.
+func bagPath(scope common.Scope, filename string) string {
 	var err error
 
-	filename := args[0].(string)
-	if !filepath.IsAbs(filename) {
+	if isBagURL(filename) {
+		return filename
+	} else if !filepath.IsAbs(filename) {
 		if bagdir, _, ok := scope.GetString("_bagdir"); ok {
 			filename = filepath.Join(bagdir, filename)
 		} else if prodfile, _, ok := scope.GetString("_prodfile"); ok {
@@ -30,32 +137,261 @@ func Bag(scope common.Scope, args ...interface{}) interface{} {
 	if filename, err = filepath.Abs(filename); err != nil {
 		panic(fmt.Errorf("bad filepath: %v\n", filename))
 	}
+	return filename
+}
+
+// bagRecords will return the records of bag `filename` in CSV
+// format.
+func bagRecords(scope common.Scope, filename string) [][]string {
+	return bagEntryOf(scope, filename, false /*raw*/).records
+}
+
+// bagData will return the content of bag `filename` as is, after
+// decompressing gzip content.
+func bagData(scope common.Scope, filename string) []byte {
+	return bagEntryOf(scope, filename, true /*raw*/).data
+}
+
+// bagEntryOf will return bag `filename`, reading it from file only
+// once, unless evicted from cache. Bags are read without holding
+// bagrw, so that a slow file or URL does not block lookups on other
+// bags.
+func bagEntryOf(scope common.Scope, filename string, raw bool) *bagEntry {
+	filename = bagPath(scope, filename)
+	cache := bagCacheOf(scope)
+
+	key := bagKey(filename, raw)
+	entry, delim, timeout := cache.lookup(key)
+	if entry != nil {
+		return entry
+	}
+	entry = &bagEntry{
+		key:      key,
+		filename: filename,
+		raw:      raw,
+		indexes:  make(map[int64]map[string][]int),
+		checked:  time.Now(),
+		derived:  make(map[string]*derivedEntry),
+	}
+	decode := func(r io.Reader) { entry.decode(r, delim) }
+	if isBagURL(filename) {
+		fetchBag(filename, timeout, decode)
+	} else {
+		entry.mtime = bagMtime(filename)
+		readBag(filename, decode)
+	}
+	return cache.insert(entry)
+}
+
+// bagKey will return the key to cache bag `filename` under, raw and
+// parsed content of the same file are cached separately.
+func bagKey(filename string, raw bool) string {
+	if raw {
+		return "raw:" + filename
+	}
+	return filename
+}
+
+// lookup will return the cached bag under `key`, evicting the bag if
+// it is stale. On a miss, nil is returned along with the delimiter
+// and timeout to read the bag with.
+func (cache *bagCache) lookup(key string) (*bagEntry, rune, time.Duration) {
+	bagrw.Lock()
+	defer bagrw.Unlock()
+	entry, ok := cache.records[key]
+	if ok && !isBagURL(entry.filename) && bagTTL > 0 &&
+		time.Since(entry.checked) > bagTTL {
+
+		entry.checked = time.Now()
+		if mtime := bagMtime(entry.filename); !mtime.Equal(entry.mtime) {
+			cache.evict(entry)
+			ok = false
+		}
+	}
+	if ok {
+		cache.lru.MoveToFront(entry.elem)
+		return entry, 0, 0
+	}
+	return nil, bagDelimiter, bagHTTPTimeout
+}
+
+// insert will add bag `entry` to cache and return it. If the bag was
+// read meanwhile by another caller, cached bag is returned instead.
+func (cache *bagCache) insert(entry *bagEntry) *bagEntry {
+	bagrw.Lock()
+	defer bagrw.Unlock()
+	if cached, ok := cache.records[entry.key]; ok {
+		cache.lru.MoveToFront(cached.elem)
+		return cached
+	}
+	entry.elem = cache.lru.PushFront(entry)
+	cache.records[entry.key] = entry
+	for cache.lru.Len() > bagCacheSize {
+		cache.evict(cache.lru.Back().Value.(*bagEntry))
+	}
+	return entry
+}
+
+// bagIndex will return the records of bag `filename` along with a
+// reverse index from the value of column `keycol` to the indices of
+// records having that value. The index is built once and cached
+// along with the bag.
+func bagIndex(
+	scope common.Scope,
+	filename string, keycol int64) ([][]string, map[string][]int) {
+
+	records := bagRecords(scope, filename)
+	filename = bagPath(scope, filename)
+	cache := bagCacheOf(scope)
+
+	bagrw.Lock()
+	defer bagrw.Unlock()
+	entry, ok := cache.records[filename]
+	if ok {
+		if index, ok := entry.indexes[keycol]; ok {
+			return entry.records, index
+		}
+		records = entry.records
+	}
+	index := make(map[string][]int)
+	for i, record := range records {
+		if keycol < int64(len(record)) {
+			index[record[keycol]] = append(index[record[keycol]], i)
+		}
+	}
+	if ok {
+		entry.indexes[keycol] = index
+	}
+	return records, index
+}
+
+// InvalidateBag will drop bag `filename`, its indexes and structures
+// derived from it, from the cache of scope. It is read again on its
+// next use.
+func InvalidateBag(scope common.Scope, filename string) {
+	filename = bagPath(scope, filename)
+	cache := bagCacheOf(scope)
+
+	bagrw.Lock()
+	defer bagrw.Unlock()
+	for _, raw := range []bool{false, true} {
+		if entry, ok := cache.records[bagKey(filename, raw)]; ok {
+			cache.evict(entry)
+		}
+	}
+}
+
+// InvalidateBagIndex will drop reverse indexes built on bag
+// `filename` from the cache of scope, while retaining its records.
+func InvalidateBagIndex(scope common.Scope, filename string) {
+	filename = bagPath(scope, filename)
+	cache := bagCacheOf(scope)
+
+	bagrw.Lock()
+	defer bagrw.Unlock()
+	if entry, ok := cache.records[filename]; ok {
+		entry.indexes = make(map[int64]map[string][]int)
+	}
+}
+
+// evict will remove bag `entry` from cache, must be called with
+// bagrw locked.
+func (cache *bagCache) evict(entry *bagEntry) {
+	cache.lru.Remove(entry.elem)
+	delete(cache.records, entry.key)
+}
+
+// bagDerived will return the structure cached under `key`, calling
+// build to construct it on a miss. It is cached along with the first
+// of bags `deps` it is derived from, and built again once any of them
+// is evicted or reloaded.
+func bagDerived(
+	key string, build func() interface{}, deps ...*bagEntry) interface{} {
 
 	bagrw.RLock()
-	records, ok := cacheBagRecords[filename]
+	d, ok := deps[0].derived[key]
 	bagrw.RUnlock()
-	if !ok {
-		records = readBag(filename)
-		bagrw.Lock()
-		cacheBagRecords[filename] = records
-		bagrw.Unlock()
+	if ok && sameBags(d.deps, deps) {
+		return d.val
 	}
-	if len(records) > 0 {
-		rnd := scope.GetRandom()
-		record := records[rnd.Intn(len(records))]
-		if len(record) > 0 {
-			return record[0]
+	val := build()
+	bagrw.Lock()
+	deps[0].derived[key] = &derivedEntry{val: val, deps: deps}
+	bagrw.Unlock()
+	return val
+}
+
+func sameBags(xs, ys []*bagEntry) bool {
+	if len(xs) != len(ys) {
+		return false
+	}
+	for i := range xs {
+		if xs[i] != ys[i] {
+			return false
 		}
 	}
-	return ""
+	return true
 }
 
-func readBag(filename string) [][]string {
+// bagCacheOf will return the bag cache for scope, attaching one if
+// scope was not built with a cache.
+func bagCacheOf(scope common.Scope) *bagCache {
+	val, _, ok := scope.Get("_bagcache")
+	if !ok {
+		val, _, _ = NewBagCache(scope).Get("_bagcache")
+	}
+	return val.(*bagCache)
+}
+
+func bagMtime(filename string) time.Time {
+	fi, err := os.Stat(filename)
+	if err != nil {
+		panic(fmt.Errorf("cannot stat file %v\n", filename))
+	}
+	return fi.ModTime()
+}
+
+// readBag will open bag `filename` and pass its content to decode.
+func readBag(filename string, decode func(io.Reader)) {
 	fd, err := os.Open(filename)
 	if err != nil {
 		panic(fmt.Errorf("cannot open file %v\n", filename))
 	}
-	records, err := csv.NewReader(fd).ReadAll()
+	defer fd.Close()
+	decode(fd)
+}
+
+// decode will read the content of bag from `r`, decompressing gzip
+// content. Files with ".gz" suffix, or starting with gzip magic bytes,
+// are treated as gzip.
+func (entry *bagEntry) decode(r io.Reader, delim rune) {
+	var err error
+
+	r = bufio.NewReader(r)
+	magic, _ := r.(*bufio.Reader).Peek(2)
+	gzipped := bytes.Equal(magic, gzipMagic)
+	if strings.HasSuffix(entry.filename, ".gz") || gzipped {
+		if r, err = gzip.NewReader(r); err != nil {
+			fmsg := "unable to read file %q in gzip format: %v\n"
+			panic(fmt.Errorf(fmsg, entry.filename, err))
+		}
+	}
+	if entry.raw {
+		if entry.data, err = ioutil.ReadAll(r); err != nil {
+			fmsg := "unable to read file %q: %v\n"
+			panic(fmt.Errorf(fmsg, entry.filename, err))
+		}
+		return
+	}
+	entry.records = parseBag(entry.filename, r, delim)
+}
+
+// parseBag will parse bag `filename` in CSV format from `r`, with
+// fields separated by `delim`.
+func parseBag(filename string, r io.Reader, delim rune) [][]string {
+	reader := csv.NewReader(r)
+	reader.Comma = delim
+	records, err := reader.ReadAll()
 	if err == nil {
 		return records
 	}
@@ -0,0 +1,53 @@
+//  Copyright (c) 2013 Couchbase, Inc.
+
+package builtin
+
+import "testing"
+import "fmt"
+import "io/ioutil"
+import "math/rand"
+import "os"
+import "path/filepath"
+
+import "github.com/prataprc/monster/common"
+
+func benchBag(b *testing.B, n, nkeys int) (common.Scope, func()) {
+	dir, err := ioutil.TempDir("", "monster")
+	if err != nil {
+		b.Fatal(err)
+	}
+	data := make([]byte, 0, n*16)
+	for i := 0; i < n; i++ {
+		data = append(data, fmt.Sprintf("key%v,%v\n", i%nkeys, i)...)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "bench"), data, 0644); err != nil {
+		b.Fatal(err)
+	}
+	globals := common.Scope{"_bagdir": dir, "_random": rand.New(rand.NewSource(1))}
+	scope := NewBagCache(common.Scope{"_globals": globals})
+	return scope, func() { os.RemoveAll(dir) }
+}
+
+func BenchmarkCondIndexed(b *testing.B) {
+	scope, cleanup := benchBag(b, 10000, 100)
+	defer cleanup()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		Bagwhere(scope, "bench", int64(1), int64(0), "key42")
+	}
+}
+
+func BenchmarkCondLinear(b *testing.B) {
+	scope, cleanup := benchBag(b, 10000, 100)
+	defer cleanup()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		values := make([]string, 0)
+		for _, record := range bagRecords(scope, "bench") {
+			if record[0] == "key42" {
+				values = append(values, record[1])
+			}
+		}
+		_ = values[scope.GetRandom().Intn(len(values))]
+	}
+}
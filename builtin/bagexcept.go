@@ -0,0 +1,35 @@
+//  Copyright (c) 2013 Couchbase, Inc.
+
+package builtin
+
+import "fmt"
+
+import "github.com/prataprc/monster/common"
+
+// Bagexcept will fetch a random value from a bag, among the records
+// whose value is not one of the excluded values.
+// args[0] - filename.
+// args[1] - column index of value.
+// args[2:] - values to exclude.
+func Bagexcept(scope common.Scope, args ...interface{}) interface{} {
+	if len(args) < 2 {
+		panic(fmt.Errorf("bagexcept expects filename and valcol\n"))
+	}
+	records, valcol := bagRecords(scope, args[0].(string)), toInt64(args[1])
+	excludes := make(map[string]bool)
+	for _, arg := range args[2:] {
+		excludes[fmt.Sprintf("%v", arg)] = true
+	}
+	values := make([]string, 0, len(records))
+	for _, record := range records {
+		if int64(len(record)) <= valcol || excludes[record[valcol]] {
+			continue
+		}
+		values = append(values, record[valcol])
+	}
+	if len(values) == 0 {
+		fmsg := "bagexcept all values in %v column %v are excluded\n"
+		panic(fmt.Errorf(fmsg, args[0], valcol))
+	}
+	return values[scope.GetRandom().Intn(len(values))]
+}
@@ -0,0 +1,35 @@
+//  Copyright (c) 2013 Couchbase, Inc.
+
+package builtin
+
+import "fmt"
+import "regexp"
+import "strconv"
+
+import "github.com/prataprc/monster/common"
+
+var bagfPlaceholder = regexp.MustCompile(`\{([0-9]+)\}`)
+
+// Bagf will fetch a random record from file and return the template,
+// with every `{n}` placeholder substituted by n-th column of the
+// record.
+// args[0] - filename.
+// args[1] - template.
+func Bagf(scope common.Scope, args ...interface{}) interface{} {
+	if len(args) < 2 {
+		panic(fmt.Errorf("bagf expects filename and template\n"))
+	}
+	records := bagRecords(scope, args[0].(string))
+	if len(records) == 0 {
+		return ""
+	}
+	record := records[scope.GetRandom().Intn(len(records))]
+	return bagfPlaceholder.ReplaceAllStringFunc(args[1].(string), func(m string) string {
+		col, _ := strconv.Atoi(m[1 : len(m)-1])
+		if col >= len(record) {
+			fmsg := "bagf placeholder %v out of range for %v\n"
+			panic(fmt.Errorf(fmsg, m, record))
+		}
+		return record[col]
+	})
+}
@@ -0,0 +1,50 @@
+//  Copyright (c) 2013 Couchbase, Inc.
+
+package builtin
+
+import "fmt"
+import "io"
+import "net/http"
+import "strings"
+import "time"
+
+// DefaultBagHTTPTimeout is the default timeout for fetching bags
+// from http or https URLs.
+const DefaultBagHTTPTimeout = 10 * time.Second
+
+var bagHTTPTimeout = DefaultBagHTTPTimeout
+
+// SetBagHTTPTimeout will set the timeout for fetching bags from
+// http or https URLs.
+func SetBagHTTPTimeout(timeout time.Duration) {
+	bagrw.Lock()
+	defer bagrw.Unlock()
+	bagHTTPTimeout = timeout
+}
+
+func isBagURL(filename string) bool {
+	return strings.HasPrefix(filename, "http://") ||
+		strings.HasPrefix(filename, "https://")
+}
+
+// fetchBag will fetch bag from `url` and pass its content to decode.
+// A failed fetch is retried once.
+func fetchBag(url string, timeout time.Duration, decode func(io.Reader)) {
+	client := &http.Client{Timeout: timeout}
+	var err error
+	for attempt := 0; attempt < 2; attempt++ {
+		var resp *http.Response
+		if resp, err = client.Get(url); err != nil {
+			continue
+		}
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			err = fmt.Errorf("status %v", resp.Status)
+			continue
+		}
+		defer resp.Body.Close()
+		decode(resp.Body)
+		return
+	}
+	panic(fmt.Errorf("cannot fetch bag %v: %v\n", url, err))
+}
@@ -0,0 +1,85 @@
+//  Copyright (c) 2013 Couchbase, Inc.
+
+package builtin
+
+import "fmt"
+import "encoding/json"
+
+import "github.com/prataprc/monster/common"
+
+// Bagjoin will inner-join two bags on a key column and return a
+// random joined row as JSON object. First record of each bag is used
+// as header, providing the keys for the object. Rows without a match
+// on the other side are skipped. Joined table is cached.
+// args[0] - left filename.
+// args[1] - key column name in left bag.
+// args[2] - right filename.
+// args[3] - key column name in right bag.
+func Bagjoin(scope common.Scope, args ...interface{}) interface{} {
+	if len(args) < 4 {
+		panic(fmt.Errorf("bagjoin expects left.csv leftkey right.csv rightkey\n"))
+	}
+	left, lkey := bagPath(scope, args[0].(string)), args[1].(string)
+	right, rkey := bagPath(scope, args[2].(string)), args[3].(string)
+	cachekey := fmt.Sprintf("bagjoin:%v:%v:%v:%v", left, lkey, right, rkey)
+	rows := bagDerived(cachekey, func() interface{} {
+		return joinBags(scope, left, lkey, right, rkey)
+	}, bagEntryOf(scope, left, false), bagEntryOf(scope, right, false)).([]string)
+	return rows[scope.GetRandom().Intn(len(rows))]
+}
+
+func joinBags(scope common.Scope, left, lkey, right, rkey string) []string {
+	lobjs, robjs := bagObjects(scope, left, lkey), bagObjects(scope, right, rkey)
+	index := make(map[string][]map[string]string)
+	for _, robj := range robjs {
+		index[robj[rkey]] = append(index[robj[rkey]], robj)
+	}
+	rows := make([]string, 0)
+	for _, lobj := range lobjs {
+		for _, robj := range index[lobj[lkey]] {
+			joined := make(map[string]string)
+			for k, v := range lobj {
+				joined[k] = v
+			}
+			for k, v := range robj {
+				joined[k] = v
+			}
+			data, err := json.Marshal(joined)
+			if err != nil {
+				panic(fmt.Errorf("unable to marshal %v: %v\n", joined, err))
+			}
+			rows = append(rows, string(data))
+		}
+	}
+	if len(rows) == 0 {
+		panic(fmt.Errorf("bagjoin no matching rows in %v and %v\n", left, right))
+	}
+	return rows
+}
+
+// bagObjects will return records of bag as objects keyed by the
+// header record, and validate that header contains `key`.
+func bagObjects(scope common.Scope, filename, key string) []map[string]string {
+	records := bagRecords(scope, filename)
+	if len(records) < 1 {
+		panic(fmt.Errorf("expected a header row in %v\n", filename))
+	}
+	header, found := records[0], false
+	for _, name := range header {
+		found = found || name == key
+	}
+	if !found {
+		panic(fmt.Errorf("column %q not found in %v\n", key, filename))
+	}
+	objs := make([]map[string]string, 0, len(records)-1)
+	for _, record := range records[1:] {
+		obj := make(map[string]string)
+		for k, field := range record {
+			if k < len(header) {
+				obj[header[k]] = field
+			}
+		}
+		objs = append(objs, obj)
+	}
+	return objs
+}
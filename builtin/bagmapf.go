@@ -0,0 +1,51 @@
+//  Copyright (c) 2013 Couchbase, Inc.
+
+package builtin
+
+import "fmt"
+import "regexp"
+import "strings"
+
+import "github.com/prataprc/monster/common"
+
+var slugSeparator = regexp.MustCompile(`[^a-z0-9]+`)
+
+// bagTransforms are the transforms that can be applied by bagmapf.
+var bagTransforms = map[string]common.FormFn{
+	"upper": Upper,
+	"lower": Lower,
+	"trim":  Trim,
+	"slug":  slugify,
+}
+
+// Bagmapf will fetch a random record from file and return its col-th
+// field, with a named transform applied to it, like "upper", "lower",
+// "trim" or "slug".
+// args[0] - filename.
+// args[1] - column index.
+// args[2] - transform name.
+func Bagmapf(scope common.Scope, args ...interface{}) interface{} {
+	if len(args) < 3 {
+		panic(fmt.Errorf("bagmapf expects filename, column and transform\n"))
+	}
+	records, col := bagRecords(scope, args[0].(string)), toInt64(args[1])
+	transform, ok := bagTransforms[args[2].(string)]
+	if !ok {
+		panic(fmt.Errorf("bagmapf unknown transform %q\n", args[2]))
+	} else if len(records) == 0 {
+		return ""
+	}
+	record := records[scope.GetRandom().Intn(len(records))]
+	if col < 0 || col >= int64(len(record)) {
+		panic(fmt.Errorf("bagmapf column %v out of range for %v\n", col, record))
+	}
+	return transform(scope, record[col])
+}
+
+// slugify will lower case args[0] and replace runs of characters
+// other than letters and digits with a single "-".
+func slugify(scope common.Scope, args ...interface{}) interface{} {
+	s := Lower(scope, args...).(string)
+	s = slugSeparator.ReplaceAllString(s, "-")
+	return strings.Trim(s, "-")
+}
@@ -0,0 +1,52 @@
+//  Copyright (c) 2013 Couchbase, Inc.
+
+package builtin
+
+import "fmt"
+
+import "github.com/prataprc/monster/common"
+
+// Bagmatch will return the output column of the record whose key
+// column matches args[3] exactly, else of the record whose key column
+// shares the longest prefix with args[3]. Ties are broken randomly.
+// args[0] - filename.
+// args[1] - column index of key.
+// args[2] - column index of output.
+// args[3] - key to match.
+func Bagmatch(scope common.Scope, args ...interface{}) interface{} {
+	if len(args) < 4 {
+		panic(fmt.Errorf("bagmatch expects filename, keycol, outcol and key\n"))
+	}
+	records := bagRecords(scope, args[0].(string))
+	keycol, outcol := toInt64(args[1]), toInt64(args[2])
+	key := fmt.Sprintf("%v", args[3])
+
+	best, matches := 0, make([]string, 0)
+	for _, record := range records {
+		if int64(len(record)) <= keycol || int64(len(record)) <= outcol {
+			continue
+		}
+		n := commonPrefix(record[keycol], key)
+		if n == len(key) && n == len(record[keycol]) {
+			n++ // exact match scores above any prefix match.
+		}
+		if n > best {
+			best, matches = n, matches[:0]
+		}
+		if n == best && n > 0 {
+			matches = append(matches, record[outcol])
+		}
+	}
+	if len(matches) == 0 {
+		panic(fmt.Errorf("bagmatch no records in %v matching %q\n", args[0], key))
+	}
+	return matches[scope.GetRandom().Intn(len(matches))]
+}
+
+func commonPrefix(a, b string) int {
+	n := 0
+	for n < len(a) && n < len(b) && a[n] == b[n] {
+		n++
+	}
+	return n
+}
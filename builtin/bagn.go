@@ -0,0 +1,37 @@
+//  Copyright (c) 2013 Couchbase, Inc.
+
+package builtin
+
+import "fmt"
+
+import "github.com/prataprc/monster/common"
+
+// Bagn will fetch a random line from file and return its col-th
+// field. If a record index is supplied, same record can be picked
+// for several columns. Out of range columns return empty string.
+// args[0] - filename.
+// args[1] - column index.
+// args[2] - optional, record index, integer or name of an integer
+// variable, taken modulo number of records.
+func Bagn(scope common.Scope, args ...interface{}) interface{} {
+	if len(args) < 2 {
+		panic(fmt.Errorf("bagn expects filename and column\n"))
+	}
+	records, col := bagRecords(scope, args[0].(string)), toInt64(args[1])
+	if len(records) == 0 {
+		return ""
+	}
+	var idx int64
+	if len(args) > 2 {
+		idx = varInt64(scope, args[2]) % int64(len(records))
+		if idx < 0 {
+			idx += int64(len(records))
+		}
+	} else {
+		idx = scope.GetRandom().Int63n(int64(len(records)))
+	}
+	if record := records[idx]; col >= 0 && col < int64(len(record)) {
+		return record[col]
+	}
+	return ""
+}
@@ -0,0 +1,40 @@
+//  Copyright (c) 2013 Couchbase, Inc.
+
+package builtin
+
+import "fmt"
+
+import "github.com/prataprc/monster/common"
+
+// Bagquota will fetch a random line from file, such that each value
+// is returned atmost `k` times in a run. Usage count of each value is
+// tracked in global scope.
+// args[0] - filename.
+// args[1] - k, quota for each value.
+func Bagquota(scope common.Scope, args ...interface{}) interface{} {
+	if len(args) < 2 {
+		panic(fmt.Errorf("bagquota expects filename and quota\n"))
+	}
+	filename, k := bagPath(scope, args[0].(string)), toInt64(args[1])
+	records := bagRecords(scope, filename)
+
+	name := "_bagquota:" + filename
+	usage, _, ok := scope.Get(name)
+	if !ok {
+		usage = make(map[string]int64)
+		scope.Set(name, usage, true /*global*/)
+	}
+	counts := usage.(map[string]int64)
+	values := make([]string, 0, len(records))
+	for _, record := range records {
+		if len(record) > 0 && counts[record[0]] < k {
+			values = append(values, record[0])
+		}
+	}
+	if len(values) == 0 {
+		panic(fmt.Errorf("bagquota exhausted quota %v for %v\n", k, filename))
+	}
+	val := values[scope.GetRandom().Intn(len(values))]
+	counts[val]++
+	return val
+}
@@ -0,0 +1,35 @@
+//  Copyright (c) 2013 Couchbase, Inc.
+
+package builtin
+
+import "fmt"
+
+import "github.com/prataprc/monster/common"
+
+// Bagrow will pick a random record from file and return its col-th
+// field. The picked record is remembered in global scope, so that
+// subsequent calls on the same file, while generating the same
+// document, return fields from the same record. Out of range
+// columns return empty string.
+// args[0] - filename.
+// args[1] - column index.
+func Bagrow(scope common.Scope, args ...interface{}) interface{} {
+	if len(args) < 2 {
+		panic(fmt.Errorf("bagrow expects filename and column\n"))
+	}
+	filename := bagPath(scope, args[0].(string))
+	records, col := bagRecords(scope, filename), toInt64(args[1])
+	if len(records) == 0 {
+		return ""
+	}
+	key := "bagrow:" + filename
+	idx, _, ok := scope.GetInt64(key)
+	if !ok || idx >= int64(len(records)) {
+		idx = scope.GetRandom().Int63n(int64(len(records)))
+		scope.Set(key, idx, true /*global*/)
+	}
+	if record := records[idx]; col >= 0 && col < int64(len(record)) {
+		return record[col]
+	}
+	return ""
+}
@@ -0,0 +1,52 @@
+//  Copyright (c) 2013 Couchbase, Inc.
+
+package builtin
+
+import "fmt"
+import "encoding/json"
+
+import "github.com/prataprc/monster/common"
+
+// Bagsample will pick `n` distinct random records from a bag and
+// return them as a JSON array of objects. The first record of the
+// bag is used as header, providing the keys for each object.
+// args[0] - filename.
+// args[1] - number of records to pick.
+func Bagsample(scope common.Scope, args ...interface{}) interface{} {
+	if len(args) < 2 {
+		panic(fmt.Errorf("bagsample expects filename and count\n"))
+	}
+	records, n := bagRecords(scope, args[0].(string)), int(toInt64(args[1]))
+	if len(records) < 1 {
+		panic(fmt.Errorf("bagsample expects a header row in %v\n", args[0]))
+	}
+	header, rows := records[0], records[1:]
+	if n < 0 || n > len(rows) {
+		fmsg := "bagsample cannot pick %v distinct rows from %v\n"
+		panic(fmt.Errorf(fmsg, n, len(rows)))
+	}
+
+	// partial Fisher-Yates over row indices.
+	rnd := scope.GetRandom()
+	indices := make([]int, len(rows))
+	for i := range indices {
+		indices[i] = i
+	}
+	objs := make([]map[string]string, 0, n)
+	for i := 0; i < n; i++ {
+		j := i + rnd.Intn(len(indices)-i)
+		indices[i], indices[j] = indices[j], indices[i]
+		obj := make(map[string]string)
+		for k, field := range rows[indices[i]] {
+			if k < len(header) {
+				obj[header[k]] = field
+			}
+		}
+		objs = append(objs, obj)
+	}
+	data, err := json.Marshal(objs)
+	if err != nil {
+		panic(fmt.Errorf("unable to marshal %v: %v\n", objs, err))
+	}
+	return string(data)
+}
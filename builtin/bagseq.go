@@ -0,0 +1,29 @@
+//  Copyright (c) 2013 Couchbase, Inc.
+
+package builtin
+
+import "fmt"
+
+import "github.com/prataprc/monster/common"
+
+// Bagseq will fetch lines from file in order, wrapping to the first
+// line after the last one. Cursor for each file is maintained in
+// global scope for the whole run.
+// args[0] - filename.
+func Bagseq(scope common.Scope, args ...interface{}) interface{} {
+	if len(args) < 1 {
+		panic(fmt.Errorf("bagseq expects filename\n"))
+	}
+	filename := bagPath(scope, args[0].(string))
+	records := bagRecords(scope, filename)
+	if len(records) == 0 {
+		return ""
+	}
+	name := "_bagseq:" + filename
+	cursor, _, _ := scope.GetInt64(name)
+	scope.Set(name, (cursor+1)%int64(len(records)), true /*global*/)
+	if record := records[cursor%int64(len(records))]; len(record) > 0 {
+		return record[0]
+	}
+	return ""
+}
@@ -0,0 +1,63 @@
+//  Copyright (c) 2013 Couchbase, Inc.
+
+package builtin
+
+import "fmt"
+import "hash/fnv"
+
+import "github.com/prataprc/monster/common"
+
+// Bagshard will partition the lines of a bag into `nshards` by
+// hashing the first field of each line, and deterministically map
+// `key` to one of the lines in the requested shard. If shard is not
+// supplied, key's own shard, refer ShardOf, is used. Same key will
+// always return the same line, across runs and contexts.
+// args[0] - filename.
+// args[1] - key.
+// args[2] - number of shards.
+// args[3] - optional, shard number, between 0 and nshards-1.
+func Bagshard(scope common.Scope, args ...interface{}) interface{} {
+	if len(args) < 3 {
+		panic(fmt.Errorf("bagshard expects filename, key and nshards\n"))
+	}
+	filename := bagPath(scope, args[0].(string))
+	key, nshards := fmt.Sprint(args[1]), toInt64(args[2])
+	if nshards < 1 {
+		panic(fmt.Errorf("bagshard invalid nshards %v\n", nshards))
+	}
+	shard := ShardOf(key, nshards)
+	if len(args) > 3 {
+		shard = toInt64(args[3])
+	}
+	if shard < 0 || shard >= nshards {
+		panic(fmt.Errorf("bagshard invalid shard %v of %v\n", shard, nshards))
+	}
+	cachekey := fmt.Sprintf("bagshard:%v:%v", filename, nshards)
+	shards := bagDerived(cachekey, func() interface{} {
+		shards := make([][]string, nshards)
+		for _, record := range bagRecords(scope, filename) {
+			if len(record) > 0 {
+				n := ShardOf(record[0], nshards)
+				shards[n] = append(shards[n], record[0])
+			}
+		}
+		return shards
+	}, bagEntryOf(scope, filename, false)).([][]string)
+	lines := shards[shard]
+	if len(lines) == 0 {
+		panic(fmt.Errorf("bagshard no lines in shard %v of %v\n", shard, filename))
+	}
+	return lines[fnvHash(key)%uint32(len(lines))]
+}
+
+// ShardOf will map `key` to one of `nshards` shards, using FNV-1a
+// hash of the key, which is stable across runs and contexts.
+func ShardOf(key string, nshards int64) int64 {
+	return int64(fnvHash(key) % uint32(nshards))
+}
+
+func fnvHash(s string) uint32 {
+	h := fnv.New32a()
+	h.Write([]byte(s))
+	return h.Sum32()
+}
@@ -0,0 +1,23 @@
+//  Copyright (c) 2013 Couchbase, Inc.
+
+package builtin
+
+import "fmt"
+
+import "github.com/prataprc/monster/common"
+
+// Bagslice will fetch a random record from file and return all its
+// columns as []string, to be used with forms like `index` and `join`.
+// Returned slice is a copy, the cached record is never exposed.
+// args[0] - filename.
+func Bagslice(scope common.Scope, args ...interface{}) interface{} {
+	if len(args) < 1 {
+		panic(fmt.Errorf("bagslice expects filename\n"))
+	}
+	records := bagRecords(scope, args[0].(string))
+	if len(records) == 0 {
+		return []string{}
+	}
+	record := records[scope.GetRandom().Intn(len(records))]
+	return append([]string(nil), record...)
+}
@@ -0,0 +1,45 @@
+//  Copyright (c) 2013 Couchbase, Inc.
+
+package builtin
+
+import "fmt"
+
+import "github.com/prataprc/monster/common"
+
+// Bagu will fetch a random line from file without replacement, such
+// that every line is returned once before the bag is exhausted.
+// Unused lines are tracked in global scope for the whole run. Once
+// exhausted it panics, or, if recycle is true, starts over.
+// args[0] - filename.
+// args[1] - optional, recycle, defaults to false.
+func Bagu(scope common.Scope, args ...interface{}) interface{} {
+	if len(args) < 1 {
+		panic(fmt.Errorf("bagu expects filename\n"))
+	}
+	filename := bagPath(scope, args[0].(string))
+	recycle := len(args) > 1 && args[1].(bool)
+	records := bagRecords(scope, filename)
+	if len(records) == 0 {
+		return ""
+	}
+
+	name := "_bagu:" + filename
+	val, _, ok := scope.Get(name)
+	unused, _ := val.([]int)
+	if !ok || (len(unused) == 0 && recycle) {
+		unused = make([]int, len(records))
+		for i := range unused {
+			unused[i] = i
+		}
+	} else if len(unused) == 0 {
+		panic(fmt.Errorf("bagu exhausted all %v lines in %v\n", len(records), filename))
+	}
+	i := scope.GetRandom().Intn(len(unused))
+	idx := unused[i]
+	unused[i] = unused[len(unused)-1]
+	scope.Set(name, unused[:len(unused)-1], true /*global*/)
+	if record := records[idx]; len(record) > 0 {
+		return record[0]
+	}
+	return ""
+}
@@ -0,0 +1,33 @@
+//  Copyright (c) 2013 Couchbase, Inc.
+
+package builtin
+
+import "fmt"
+
+import "github.com/prataprc/monster/common"
+
+// Bagwhere will fetch a random value from a bag, among the records
+// whose filter column matches the filter value.
+// args[0] - filename.
+// args[1] - column index of value.
+// args[2] - column index to filter on.
+// args[3] - filter value.
+func Bagwhere(scope common.Scope, args ...interface{}) interface{} {
+	if len(args) < 4 {
+		panic(fmt.Errorf("bagwhere expects filename, valcol, filtercol and filterval\n"))
+	}
+	valcol, filtercol := toInt64(args[1]), toInt64(args[2])
+	filterval := fmt.Sprintf("%v", args[3])
+	records, index := bagIndex(scope, args[0].(string), filtercol)
+	values := make([]string, 0)
+	for _, i := range index[filterval] {
+		if record := records[i]; int64(len(record)) > valcol {
+			values = append(values, record[valcol])
+		}
+	}
+	if len(values) == 0 {
+		fmsg := "bagwhere no records in %v with column %v as %q\n"
+		panic(fmt.Errorf(fmsg, args[0], filtercol, filterval))
+	}
+	return values[scope.GetRandom().Intn(len(values))]
+}
@@ -0,0 +1,37 @@
+//  Copyright (c) 2013 Couchbase, Inc.
+
+package builtin
+
+import "fmt"
+import "math"
+
+import "github.com/prataprc/monster/common"
+
+// benfordWeights are probabilities of leading digits 1..9 as per
+// Benford's law, log10(1 + 1/d).
+var benfordWeights = func() []float64 {
+	weights := make([]float64, 9)
+	for d := 1; d <= 9; d++ {
+		weights[d-1] = math.Log10(1 + 1/float64(d))
+	}
+	return weights
+}()
+
+// Benford will return an int64 number whose leading digit follows
+// Benford's law and remaining digits are uniformly random.
+// args[0] - number of digits, between 1 and 18.
+func Benford(scope common.Scope, args ...interface{}) interface{} {
+	if len(args) < 1 {
+		panic(fmt.Errorf("benford expects number of digits\n"))
+	}
+	digits := toInt64(args[0])
+	if digits < 1 || digits > 18 {
+		panic(fmt.Errorf("benford digits %v not in 1..18\n", digits))
+	}
+	rnd := scope.GetRandom()
+	n := int64(pickWeighted(rnd, benfordWeights) + 1)
+	for i := int64(1); i < digits; i++ {
+		n = n*10 + rnd.Int63n(10)
+	}
+	return n
+}
@@ -0,0 +1,34 @@
+//  Copyright (c) 2013 Couchbase, Inc.
+
+package builtin
+
+import "fmt"
+import "math"
+
+import "github.com/prataprc/monster/common"
+
+// Bivariate will sample a correlated pair from a bivariate normal
+// distribution, using the Cholesky decomposition of its covariance,
+// and return it as "x,y".
+// args[0] - mean of x.
+// args[1] - mean of y.
+// args[2] - standard deviation of x.
+// args[3] - standard deviation of y.
+// args[4] - correlation between x and y, between -1 and 1.
+func Bivariate(scope common.Scope, args ...interface{}) interface{} {
+	if len(args) < 5 {
+		panic(fmt.Errorf("bivariate expects mean1 mean2 sd1 sd2 corr\n"))
+	}
+	mean1, mean2 := toFloat64(args[0]), toFloat64(args[1])
+	sd1, sd2, corr := toFloat64(args[2]), toFloat64(args[3]), toFloat64(args[4])
+	if sd1 < 0 || sd2 < 0 {
+		panic(fmt.Errorf("bivariate sd %v, %v cannot be negative\n", sd1, sd2))
+	} else if corr < -1 || corr > 1 {
+		panic(fmt.Errorf("bivariate corr %v not in [-1,1]\n", corr))
+	}
+	rnd := scope.GetRandom()
+	z1, z2 := rnd.NormFloat64(), rnd.NormFloat64()
+	x := mean1 + sd1*z1
+	y := mean2 + sd2*(corr*z1+math.Sqrt(1-corr*corr)*z2)
+	return fmt.Sprintf("%v,%v", x, y)
+}
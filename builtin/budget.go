@@ -0,0 +1,42 @@
+//  Copyright (c) 2013 Couchbase, Inc.
+
+package builtin
+
+import "fmt"
+
+import "github.com/prataprc/monster/common"
+
+// Budget will return the numeric draw, clamped such that the running
+// total of draws under `name` never exceeds `cap`. Once the budget is
+// exhausted it returns 0. Running total is tracked in global scope.
+// If both cap and draw are integers an int64 is returned, else
+// float64.
+// args[0] - name to track the budget under.
+// args[1] - cap, budget for the run.
+// args[2] - numeric draw, typically a form like (range 1 100).
+func Budget(scope common.Scope, args ...interface{}) interface{} {
+	if len(args) < 3 {
+		panic(fmt.Errorf("budget expects name, cap and form\n"))
+	}
+	name := "_budget:" + args[0].(string)
+	cap, draw := toFloat64(args[1]), toFloat64(args[2])
+	if draw < 0 {
+		panic(fmt.Errorf("budget draw %v cannot be negative\n", draw))
+	}
+	spent := 0.0
+	if val, _, ok := scope.Get(name); ok {
+		spent = val.(float64)
+	}
+	if spent+draw > cap {
+		draw = cap - spent
+		if draw < 0 {
+			draw = 0
+		}
+	}
+	scope.Set(name, spent+draw, true /*global*/)
+	_, iok := args[1].(int64)
+	if _, dok := args[2].(int64); iok && dok {
+		return int64(draw)
+	}
+	return draw
+}
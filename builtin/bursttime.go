@@ -0,0 +1,51 @@
+//  Copyright (c) 2013 Couchbase, Inc.
+
+package builtin
+
+import "fmt"
+import "time"
+
+import "github.com/prataprc/monster/common"
+
+// DefaultBurstProb and DefaultBurstSpread are default probability
+// of starting a new burst and maximum gap between timestamps within
+// a burst, for `bursttime`.
+const DefaultBurstProb = 0.1
+const DefaultBurstSpread = 10 * time.Second
+
+// Bursttime will generate timestamps clustered into bursts. With
+// probability `burstprob` a new burst is started at a random time
+// within the default range, otherwise a time within `spread` after
+// the previous timestamp is picked. Previous timestamp is tracked in
+// global scope.
+// args[0] - golang time layout, like time.RFC3339.
+// args[1] - optional, probability of starting a new burst.
+// args[2] - optional, spread as golang duration like "10s", or seconds.
+func Bursttime(scope common.Scope, args ...interface{}) interface{} {
+	if len(args) < 1 {
+		panic(fmt.Errorf("bursttime expects a layout\n"))
+	}
+	burstprob, spread := DefaultBurstProb, DefaultBurstSpread
+	if len(args) > 1 {
+		burstprob = toFloat64(args[1])
+	}
+	if len(args) > 2 {
+		spread = toDuration(args[2])
+	}
+	if burstprob < 0 || burstprob > 1 || spread <= 0 {
+		panic(fmt.Errorf("bursttime invalid prob %v or spread %v\n", burstprob, spread))
+	}
+
+	rnd := scope.GetRandom()
+	prev, _, ok := scope.Get("_bursttime")
+	var t time.Time
+	if !ok || rnd.Float64() < burstprob {
+		start, _ := time.Parse(time.RFC3339, DefaultBusinessFrom)
+		end, _ := time.Parse(time.RFC3339, DefaultBusinessTill)
+		t = start.Add(time.Duration(rnd.Int63n(int64(end.Sub(start)))))
+	} else {
+		t = prev.(time.Time).Add(time.Duration(rnd.Int63n(int64(spread))))
+	}
+	scope.Set("_bursttime", t, true /*global*/)
+	return t.Format(args[0].(string))
+}
@@ -0,0 +1,69 @@
+//  Copyright (c) 2013 Couchbase, Inc.
+
+package builtin
+
+import "fmt"
+import "time"
+
+import "github.com/prataprc/monster/common"
+
+// DefaultBusinessFrom and DefaultBusinessTill are the default range
+// of dates for `businesstime`.
+const DefaultBusinessFrom = "2010-01-01T00:00:00Z"
+const DefaultBusinessTill = "2020-01-01T00:00:00Z"
+
+// Businesstime will randomly pick a weekday between args[1] and
+// args[2], and a time of day within business hours, and return it
+// formatted using the layout in args[0]. Picked time is always within
+// [args[1], args[2]).
+// args[0] - golang time layout, like time.RFC3339.
+// args[1] - optional, start date in RFC3339 format.
+// args[2] - optional, end date in RFC3339 format.
+// args[3] - optional, start of business hour, defaults to 9.
+// args[4] - optional, end of business hour, defaults to 17.
+func Businesstime(scope common.Scope, args ...interface{}) interface{} {
+	if len(args) < 1 {
+		panic(fmt.Errorf("businesstime expects a layout\n"))
+	}
+	layout, from, till := args[0].(string), DefaultBusinessFrom, DefaultBusinessTill
+	if len(args) > 2 {
+		from, till = args[1].(string), args[2].(string)
+	}
+	starthr, endhr := int64(9), int64(17)
+	if len(args) > 4 {
+		starthr, endhr = toInt64(args[3]), toInt64(args[4])
+	}
+	if starthr < 0 || endhr > 24 || starthr >= endhr {
+		panic(fmt.Errorf("invalid business hours %v-%v\n", starthr, endhr))
+	}
+	start, err := time.Parse(time.RFC3339, from)
+	if err != nil {
+		panic(fmt.Errorf("parsing start date %v: %v\n", from, err))
+	}
+	end, err := time.Parse(time.RFC3339, till)
+	if err != nil {
+		panic(fmt.Errorf("parsing end date %v: %v\n", till, err))
+	}
+	begin := start
+	start = time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, start.Location())
+	days := int((end.Sub(start) + 24*time.Hour - 1) / (24 * time.Hour))
+	if days < 1 {
+		panic(fmt.Errorf("businesstime expects atleast a day in %v-%v\n", from, till))
+	}
+
+	// pick again when the time falls outside [from, till), which can
+	// happen on the first and the last day of the range.
+	rnd := scope.GetRandom()
+	for i := 0; ; i++ {
+		day := start.AddDate(0, 0, rnd.Intn(days))
+		secs := rnd.Int63n((endhr - starthr) * 3600)
+		t := day.Add(time.Duration(starthr)*time.Hour + time.Duration(secs)*time.Second)
+		wd := day.Weekday()
+		weekday := wd != time.Saturday && wd != time.Sunday
+		if weekday && !t.Before(begin) && t.Before(end) {
+			return t.Format(layout)
+		} else if i > 1000 {
+			panic(fmt.Errorf("no business time between %v and %v\n", from, till))
+		}
+	}
+}
@@ -0,0 +1,80 @@
+//  Copyright (c) 2013 Couchbase, Inc.
+
+package builtin
+
+import "fmt"
+import "strconv"
+
+import "github.com/prataprc/monster/common"
+
+// Checkid will generate a random body of `ndigits` digits, append a
+// check digit computed by algorithm, and return it with prefix.
+// args[0] - prefix, not included in the checksum.
+// args[1] - number of digits in the body.
+// args[2] - algorithm, "luhn" as used by card numbers, "mod10" with
+// 3-1 weights as used by GTIN/EAN, or "mod11" with 2-7 weights where
+// check digit 10 is "X".
+func Checkid(scope common.Scope, args ...interface{}) interface{} {
+	if len(args) < 3 {
+		panic(fmt.Errorf("checkid expects prefix, ndigits and algorithm\n"))
+	}
+	prefix, ndigits, algorithm := args[0].(string), toInt64(args[1]), args[2].(string)
+	if ndigits < 1 {
+		panic(fmt.Errorf("checkid ndigits %v must be positive\n", ndigits))
+	}
+	rnd := scope.GetRandom()
+	body := make([]byte, ndigits)
+	for i := range body {
+		body[i] = byte('0' + rnd.Intn(10))
+	}
+	return prefix + string(body) + CheckDigit(algorithm, string(body))
+}
+
+// CheckDigit will compute the check digit for `digits` using
+// algorithm, one of "luhn", "mod10", "mod11".
+func CheckDigit(algorithm, digits string) string {
+	sum := 0
+	for i := len(digits) - 1; i >= 0; i-- {
+		d := int(digits[i] - '0')
+		if d < 0 || d > 9 {
+			panic(fmt.Errorf("checkid expects digits, got %q\n", digits))
+		}
+		pos := len(digits) - 1 - i // position from right, starting 0.
+		switch algorithm {
+		case "luhn":
+			if pos%2 == 0 {
+				if d *= 2; d > 9 {
+					d -= 9
+				}
+			}
+			sum += d
+		case "mod10":
+			if pos%2 == 0 {
+				d *= 3
+			}
+			sum += d
+		case "mod11":
+			sum += d * (2 + pos%6)
+		default:
+			panic(fmt.Errorf("checkid unknown algorithm %q\n", algorithm))
+		}
+	}
+	if algorithm == "mod11" {
+		switch check := (11 - sum%11) % 11; check {
+		case 10:
+			return "X"
+		default:
+			return strconv.Itoa(check)
+		}
+	}
+	return strconv.Itoa((10 - sum%10) % 10)
+}
+
+// ValidCheckID will validate that the last character of `id` is the
+// check digit for the rest of it, using algorithm.
+func ValidCheckID(algorithm, id string) bool {
+	if len(id) < 2 {
+		return false
+	}
+	return CheckDigit(algorithm, id[:len(id)-1]) == id[len(id)-1:]
+}
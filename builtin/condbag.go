@@ -0,0 +1,33 @@
+//  Copyright (c) 2013 Couchbase, Inc.
+
+package builtin
+
+import "fmt"
+
+import "github.com/prataprc/monster/common"
+
+// Condbag will return a random value from column `valcol` among the
+// records whose column `keycol` equals key, looked up using the
+// bag's cached reverse index on `keycol`.
+// args[0] - filename.
+// args[1] - column index of key.
+// args[2] - column index of value.
+// args[3] - key.
+func Condbag(scope common.Scope, args ...interface{}) interface{} {
+	if len(args) < 4 {
+		panic(fmt.Errorf("condbag expects filename, keycol, valcol and key\n"))
+	}
+	filename := bagPath(scope, args[0].(string))
+	kcol, vcol, key := toInt64(args[1]), toInt64(args[2]), fmt.Sprint(args[3])
+	records, index := bagIndex(scope, filename, kcol)
+	rows, ok := index[key]
+	if !ok {
+		panic(fmt.Errorf("condbag no records for key %q in %v\n", key, filename))
+	}
+	record := records[rows[scope.GetRandom().Intn(len(rows))]]
+	if vcol >= int64(len(record)) {
+		fmsg := "condbag column out of range in %v: %v\n"
+		panic(fmt.Errorf(fmsg, filename, record))
+	}
+	return record[vcol]
+}
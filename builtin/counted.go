@@ -0,0 +1,22 @@
+//  Copyright (c) 2013 Couchbase, Inc.
+
+package builtin
+
+import "fmt"
+
+import "github.com/prataprc/monster/common"
+
+// Counted will randomly pick one of the passed argument, like
+// `choice`, and count the selection under label. Counts can be
+// fetched using common.SelectionCounts().
+// args[0] - label.
+// args[1:] - options to choose from.
+func Counted(scope common.Scope, args ...interface{}) interface{} {
+	if len(args) < 2 {
+		panic(fmt.Errorf("counted expects label and atleast one option\n"))
+	}
+	label, options := args[0].(string), args[1:]
+	option := options[scope.GetRandom().Intn(len(options))]
+	common.CountSelection(scope, label, fmt.Sprint(option))
+	return option
+}
@@ -0,0 +1,52 @@
+//  Copyright (c) 2013 Couchbase, Inc.
+
+package builtin
+
+import "fmt"
+import "strings"
+
+import "github.com/prataprc/monster/common"
+
+// coverState tracks the options yet to be covered, and the number of
+// records generated, in the current window of coverchoice.
+type coverState struct {
+	pending []int
+	count   int64
+}
+
+// Coverchoice will pick one of the values, such that every value is
+// picked at least once over `total` records. Values are first cycled
+// through once in random order, and then picked randomly for the rest
+// of the `total` records, after which the cycle starts over. State is
+// tracked in global scope for the whole run.
+// args[0] - total, number of records over which to cover all values.
+// args[1:] - values to choose from.
+func Coverchoice(scope common.Scope, args ...interface{}) interface{} {
+	if len(args) < 2 {
+		panic(fmt.Errorf("coverchoice expects total and values\n"))
+	}
+	total, values := toInt64(args[0]), args[1:]
+	if total <= 0 {
+		panic(fmt.Errorf("coverchoice total %v must be positive\n", total))
+	}
+	keys := make([]string, 0, len(values))
+	for _, value := range values {
+		keys = append(keys, fmt.Sprintf("%q", fmt.Sprint(value)))
+	}
+	name := "_cover:" + strings.Join(keys, ",")
+
+	rnd := scope.GetRandom()
+	val, _, ok := scope.Get(name)
+	state, _ := val.(*coverState)
+	if !ok || state.count >= total {
+		state = &coverState{pending: rnd.Perm(len(values))}
+		scope.Set(name, state, true /*global*/)
+	}
+	state.count++
+	if len(state.pending) > 0 {
+		idx := state.pending[0]
+		state.pending = state.pending[1:]
+		return values[idx]
+	}
+	return values[rnd.Intn(len(values))]
+}
@@ -0,0 +1,17 @@
+//  Copyright (c) 2013 Couchbase, Inc.
+
+package builtin
+
+import "fmt"
+
+import "github.com/prataprc/monster/common"
+
+// Debugchoice is same as Choice, and additionally writes the picked
+// argument to scope's debug writer, if set.
+func Debugchoice(scope common.Scope, args ...interface{}) interface{} {
+	val := Choice(scope, args...)
+	if w, ok := scope.GetDebugWriter(); ok {
+		fmt.Fprintf(w, "debugchoice: picked %v from %v\n", val, args)
+	}
+	return val
+}
@@ -8,7 +8,8 @@ import "github.com/prataprc/monster/common"
 
 var _ = fmt.Sprintf("dummy")
 
-// Dec will decrement a variable.
+// Dec will decrement a variable and return its new value as Counter,
+// or empty string if the variable is not defined.
 // args[0] - variable name
 // args[1] - quantum of value to decrement
 // if variable name is present in local scope it will be used,
@@ -21,6 +22,7 @@ func Dec(scope common.Scope, args ...interface{}) interface{} {
 	vali, g, ok := scope.Get(name)
 	if ok {
 		scope.Set(name, vali.(int64)-by, g)
+		return Counter(vali.(int64) - by)
 	}
 	return ""
 }
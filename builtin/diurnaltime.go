@@ -0,0 +1,51 @@
+//  Copyright (c) 2013 Couchbase, Inc.
+
+package builtin
+
+import "fmt"
+import "time"
+
+import "github.com/prataprc/monster/common"
+
+// DiurnalCurve is the relative activity for each hour of the day,
+// peaking around midday and in the evening.
+var DiurnalCurve = []float64{
+	2, 1, 1, 1, 1, 2, 4, 6, 8, 9, 10, 11, // 00:00 - 11:00
+	12, 11, 10, 9, 9, 10, 12, 14, 14, 12, 8, 4, // 12:00 - 23:00
+}
+
+// Diurnaltime will randomly pick a day between args[1] and args[2],
+// an hour of the day weighted by DiurnalCurve, and a random minute
+// and second, and return it formatted using the layout in args[0].
+// args[0] - golang time layout, like time.RFC3339.
+// args[1] - optional, start date in RFC3339 format.
+// args[2] - optional, end date in RFC3339 format.
+func Diurnaltime(scope common.Scope, args ...interface{}) interface{} {
+	if len(args) < 1 {
+		panic(fmt.Errorf("diurnaltime expects a layout\n"))
+	}
+	layout, from, till := args[0].(string), DefaultBusinessFrom, DefaultBusinessTill
+	if len(args) > 2 {
+		from, till = args[1].(string), args[2].(string)
+	}
+	start, err := time.Parse(time.RFC3339, from)
+	if err != nil {
+		panic(fmt.Errorf("parsing start date %v: %v\n", from, err))
+	}
+	end, err := time.Parse(time.RFC3339, till)
+	if err != nil {
+		panic(fmt.Errorf("parsing end date %v: %v\n", till, err))
+	}
+	start = time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, start.Location())
+	days := int(end.Sub(start).Hours() / 24)
+	if days < 1 {
+		panic(fmt.Errorf("diurnaltime expects atleast a day in %v-%v\n", from, till))
+	}
+
+	rnd := scope.GetRandom()
+	day := start.AddDate(0, 0, rnd.Intn(days))
+	hour := pickWeighted(rnd, DiurnalCurve)
+	secs := rnd.Intn(3600)
+	t := day.Add(time.Duration(hour)*time.Hour + time.Duration(secs)*time.Second)
+	return t.Format(layout)
+}
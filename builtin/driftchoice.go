@@ -0,0 +1,42 @@
+//  Copyright (c) 2013 Couchbase, Inc.
+
+package builtin
+
+import "fmt"
+
+import "github.com/prataprc/monster/common"
+
+// Driftchoice will pick one of the values, where the weight of each
+// value drifts linearly from its initial weight to its final weight
+// as `idx` moves from 0 to `total`. Beyond `total` final weights
+// are used.
+// args[0] - idx, integer or name of an integer variable.
+// args[1] - total, number of records over which weights drift.
+// args[2], args[5] ... - value.
+// args[3], args[6] ... - initial weight of value.
+// args[4], args[7] ... - final weight of value.
+func Driftchoice(scope common.Scope, args ...interface{}) interface{} {
+	if len(args) < 5 || (len(args)-2)%3 != 0 {
+		panic(fmt.Errorf("driftchoice expects idxvar, total and value/from/to groups\n"))
+	}
+	idx, total := varInt64(scope, args[0]), toInt64(args[1])
+	if total <= 0 {
+		panic(fmt.Errorf("driftchoice total %v must be positive\n", total))
+	}
+	frac := float64(idx) / float64(total)
+	if frac < 0 {
+		frac = 0
+	} else if frac > 1 {
+		frac = 1
+	}
+	opts := args[2:]
+	weights := make([]float64, 0, len(opts)/3)
+	for i := 0; i < len(opts); i += 3 {
+		from, to := toFloat64(opts[i+1]), toFloat64(opts[i+2])
+		if from < 0 || to < 0 {
+			panic(fmt.Errorf("driftchoice weights %v, %v cannot be negative\n", from, to))
+		}
+		weights = append(weights, from+(to-from)*frac)
+	}
+	return opts[pickWeighted(scope.GetRandom(), weights)*3]
+}
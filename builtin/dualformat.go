@@ -0,0 +1,21 @@
+//  Copyright (c) 2013 Couchbase, Inc.
+
+package builtin
+
+import "fmt"
+
+import "github.com/prataprc/monster/common"
+
+// Dualformat will define the raw value as `rawname` in local scope,
+// and return it formatted for display.
+// args[0] - raw value, typically a form like (rangef 0.0 100.0).
+// args[1] - name to define the raw value as.
+// args[2] - sprintf-style layout for display, like "$%.2f".
+func Dualformat(scope common.Scope, args ...interface{}) interface{} {
+	if len(args) < 3 {
+		panic(fmt.Errorf("dualformat expects form, rawname and dispfmt\n"))
+	}
+	raw, rawname, dispfmt := args[0], args[1].(string), args[2].(string)
+	scope.Set(rawname, raw, false /*global*/)
+	return fmt.Sprintf(dispfmt, raw)
+}
@@ -0,0 +1,36 @@
+//  Copyright (c) 2013 Couchbase, Inc.
+
+package builtin
+
+import "fmt"
+import "strconv"
+
+import "github.com/prataprc/monster/common"
+
+// Dynwchoice will pick one of the values based on weights that are
+// evaluated at call time, typically from variables in scope. Weights
+// supplied as numeric strings, say from a bag, are parsed as float.
+// args[0], args[2] ... - weight, must not be negative.
+// args[1], args[3] ... - value.
+func Dynwchoice(scope common.Scope, args ...interface{}) interface{} {
+	if len(args) < 2 || len(args)%2 != 0 {
+		panic(fmt.Errorf("dynwchoice expects weight/value pairs\n"))
+	}
+	weights := make([]float64, 0, len(args)/2)
+	for i := 0; i < len(args); i += 2 {
+		var w float64
+		if s, ok := args[i].(string); ok {
+			var err error
+			if w, err = strconv.ParseFloat(s, 64); err != nil {
+				panic(fmt.Errorf("dynwchoice invalid weight %q\n", s))
+			}
+		} else {
+			w = toFloat64(args[i])
+		}
+		if w < 0 {
+			panic(fmt.Errorf("dynwchoice weight %v cannot be negative\n", w))
+		}
+		weights = append(weights, w)
+	}
+	return args[pickWeighted(scope.GetRandom(), weights)*2+1]
+}
@@ -0,0 +1,43 @@
+//  Copyright (c) 2013 Couchbase, Inc.
+
+package builtin
+
+import "strings"
+
+import "github.com/prataprc/monster/common"
+
+var emailSyllables = []string{
+	"al", "an", "ba", "be", "ca", "da", "el", "en", "fa", "ja", "jo",
+	"ka", "li", "lu", "ma", "mi", "na", "no", "ra", "ri", "sa", "ta",
+	"to", "vi",
+}
+
+var emailDomains = []string{
+	"example.com", "example.org", "example.net", "mail.com", "inbox.org",
+	"post.net",
+}
+
+// Email will compose an email address, picking local-part and domain
+// from bags, or from built-in lists when bags are not supplied. The
+// address is lowercased and spaces are stripped from the local-part.
+// args[0] - optional, bag filename for local-part.
+// args[1] - optional, bag filename for domain.
+func Email(scope common.Scope, args ...interface{}) interface{} {
+	rnd := scope.GetRandom()
+	var local, domain string
+	if len(args) > 0 {
+		local = Bag(scope, args[0]).(string)
+	} else {
+		n := 2 + rnd.Intn(3)
+		for i := 0; i < n; i++ {
+			local += emailSyllables[rnd.Intn(len(emailSyllables))]
+		}
+	}
+	if len(args) > 1 {
+		domain = Bag(scope, args[1]).(string)
+	} else {
+		domain = emailDomains[rnd.Intn(len(emailDomains))]
+	}
+	local = strings.Replace(local, " ", "", -1)
+	return strings.ToLower(local + "@" + domain)
+}
@@ -0,0 +1,33 @@
+//  Copyright (c) 2013 Couchbase, Inc.
+
+package builtin
+
+import "fmt"
+import "time"
+
+import "github.com/prataprc/monster/common"
+
+// Epoch will pick a uniformly random instant between start and end
+// dates and return it as int64 unix epoch in the requested unit.
+// args[0] - start date in RFC3339 format.
+// args[1] - end date in RFC3339 format.
+// args[2] - optional, unit one of "s", "ms", "ns", defaults to "s".
+func Epoch(scope common.Scope, args ...interface{}) interface{} {
+	if len(args) < 2 {
+		panic(fmt.Errorf("epoch expects start and end dates\n"))
+	}
+	from, till, unit := args[0].(string), args[1].(string), "s"
+	if len(args) > 2 {
+		unit = args[2].(string)
+	}
+	t := randInstant(scope, "epoch", from, till)
+	switch unit {
+	case "s":
+		return t.Unix()
+	case "ms":
+		return t.UnixNano() / int64(time.Millisecond)
+	case "ns":
+		return t.UnixNano()
+	}
+	panic(fmt.Errorf("epoch unknown unit %q\n", unit))
+}
@@ -0,0 +1,35 @@
+//  Copyright (c) 2013 Couchbase, Inc.
+
+package builtin
+
+import "fmt"
+import "io/ioutil"
+import "strconv"
+import "strings"
+
+import "github.com/prataprc/monster/common"
+
+// Fileseq will return the options in round-robin, with the cursor
+// persisted in a file so that successive runs continue the sequence.
+// A missing or corrupt cursor file starts from the first option.
+// args[0] - cursor filename, resolved like bag files.
+// args[1:] - options.
+func Fileseq(scope common.Scope, args ...interface{}) interface{} {
+	if len(args) < 2 {
+		panic(fmt.Errorf("fileseq expects cursorfile and atleast one option\n"))
+	}
+	filename, options := bagPath(scope, args[0].(string)), args[1:]
+	var cursor int64
+	if data, err := ioutil.ReadFile(filename); err == nil {
+		s := strings.TrimSpace(string(data))
+		if cursor, err = strconv.ParseInt(s, 10, 64); err != nil || cursor < 0 {
+			cursor = 0
+		}
+	}
+	cursor %= int64(len(options))
+	data := []byte(strconv.FormatInt(cursor+1, 10) + "\n")
+	if err := ioutil.WriteFile(filename, data, 0644); err != nil {
+		panic(fmt.Errorf("fileseq cannot write cursor %v: %v\n", filename, err))
+	}
+	return options[cursor]
+}
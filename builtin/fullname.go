@@ -0,0 +1,53 @@
+//  Copyright (c) 2013 Couchbase, Inc.
+
+package builtin
+
+import "fmt"
+
+import "github.com/prataprc/monster/common"
+
+var firstNames = map[string][]string{
+	"en": {"James", "Mary", "John", "Patricia", "Robert", "Jennifer",
+		"Michael", "Linda", "William", "Elizabeth"},
+	"fr": {"Jean", "Marie", "Pierre", "Nathalie", "Michel", "Isabelle",
+		"Philippe", "Sylvie", "Alain", "Catherine"},
+	"de": {"Peter", "Ursula", "Wolfgang", "Monika", "Klaus", "Petra",
+		"Jürgen", "Sabine", "Dieter", "Renate"},
+	"es": {"Antonio", "Carmen", "José", "Josefa", "Manuel", "Isabel",
+		"Francisco", "Dolores", "Juan", "Pilar"},
+}
+
+var lastNames = map[string][]string{
+	"en": {"Smith", "Johnson", "Williams", "Brown", "Jones", "Miller",
+		"Davis", "Wilson", "Taylor", "Clark"},
+	"fr": {"Martin", "Bernard", "Dubois", "Thomas", "Robert", "Richard",
+		"Petit", "Durand", "Leroy", "Moreau"},
+	"de": {"Müller", "Schmidt", "Schneider", "Fischer", "Weber", "Meyer",
+		"Wagner", "Becker", "Schulz", "Hoffmann"},
+	"es": {"García", "González", "Rodríguez", "Fernández", "López",
+		"Martínez", "Sánchez", "Pérez", "Gómez", "Martín"},
+}
+
+// Fullname will randomly pick a first-name and last-name for a
+// locale, define them as `first`, `last` and `full` in local scope
+// and return the full name.
+// args[0] - optional, locale among "en", "fr", "de", "es",
+// defaults to "en".
+func Fullname(scope common.Scope, args ...interface{}) interface{} {
+	locale := "en"
+	if len(args) > 0 {
+		locale = args[0].(string)
+	}
+	firsts, ok := firstNames[locale]
+	if !ok {
+		panic(fmt.Errorf("fullname unknown locale %q\n", locale))
+	}
+	lasts := lastNames[locale]
+	rnd := scope.GetRandom()
+	first, last := firsts[rnd.Intn(len(firsts))], lasts[rnd.Intn(len(lasts))]
+	full := first + " " + last
+	scope.Set("first", first, false /*global*/)
+	scope.Set("last", last, false /*global*/)
+	scope.Set("full", full, false /*global*/)
+	return full
+}
@@ -0,0 +1,25 @@
+//  Copyright (c) 2013 Couchbase, Inc.
+
+package builtin
+
+import "fmt"
+import "math"
+
+import "github.com/prataprc/monster/common"
+
+// Geometric will return the number of trials, as int64, until the
+// first success with success probability `p` for each trial.
+// args[0] - p, in (0, 1].
+func Geometric(scope common.Scope, args ...interface{}) interface{} {
+	if len(args) < 1 {
+		panic(fmt.Errorf("geometric expects p\n"))
+	}
+	p := toFloat64(args[0])
+	if p <= 0 || p > 1 {
+		panic(fmt.Errorf("geometric p %v not in (0,1]\n", p))
+	} else if p == 1 {
+		return int64(1)
+	}
+	u := 1.0 - scope.GetRandom().Float64() // (0, 1]
+	return int64(math.Max(1, math.Ceil(math.Log(u)/math.Log(1-p))))
+}
@@ -0,0 +1,42 @@
+//  Copyright (c) 2013 Couchbase, Inc.
+
+package builtin
+
+import "fmt"
+import "strings"
+
+import "github.com/prataprc/monster/common"
+
+// Hier3 will pick a random record from file and define the fields
+// of its three hierarchical levels, like region, country and city, as
+// `level1`, `level2` and `level3` in local scope. Returns the levels
+// joined as a path.
+// args[0] - filename.
+// args[1] - column index of first level.
+// args[2] - column index of second level.
+// args[3] - column index of third level.
+// args[4] - optional, path separator, defaults to "/".
+func Hier3(scope common.Scope, args ...interface{}) interface{} {
+	if len(args) < 4 {
+		panic(fmt.Errorf("hier3 expects filename and three columns\n"))
+	}
+	records := bagRecords(scope, args[0].(string))
+	sep := "/"
+	if len(args) > 4 {
+		sep = args[4].(string)
+	}
+	if len(records) == 0 {
+		return ""
+	}
+	record := records[scope.GetRandom().Intn(len(records))]
+	levels := make([]string, 0, 3)
+	for i, arg := range args[1:4] {
+		col := toInt64(arg)
+		if col < 0 || col >= int64(len(record)) {
+			panic(fmt.Errorf("hier3 column %v out of range for %v\n", col, record))
+		}
+		levels = append(levels, record[col])
+		scope.Set(fmt.Sprintf("level%v", i+1), record[col], false /*global*/)
+	}
+	return strings.Join(levels, sep)
+}
@@ -0,0 +1,35 @@
+//  Copyright (c) 2013 Couchbase, Inc.
+
+package builtin
+
+import "fmt"
+
+import "github.com/prataprc/monster/common"
+
+var _ = fmt.Sprintf("dummy")
+
+// Histogram will pick a bucket based on its frequency and return
+// a uniformly random float64 within the bucket's edges.
+// args[0] - lower edge of the first bucket
+// args[1], args[3] ... args[N-1] - upper edge of the bucket
+// args[2], args[4] ... args[N] - frequency of the bucket
+// edges are expected to be in increasing order.
+func Histogram(scope common.Scope, args ...interface{}) interface{} {
+	if len(args) < 3 || len(args)%2 == 0 {
+		panic(fmt.Errorf("histogram expects e0 followed by edge/freq pairs\n"))
+	}
+	edges := []float64{toFloat64(args[0])}
+	freqs := make([]float64, 0, len(args)/2)
+	for i := 1; i < len(args); i += 2 {
+		edge, freq := toFloat64(args[i]), toFloat64(args[i+1])
+		if edge <= edges[len(edges)-1] {
+			panic(fmt.Errorf("histogram edges not in order at %v\n", edge))
+		} else if freq < 0 {
+			panic(fmt.Errorf("histogram negative frequency %v\n", freq))
+		}
+		edges, freqs = append(edges, edge), append(freqs, freq)
+	}
+	rnd := scope.GetRandom()
+	i := pickWeighted(rnd, freqs)
+	return edges[i] + rnd.Float64()*(edges[i+1]-edges[i])
+}
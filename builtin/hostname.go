@@ -0,0 +1,26 @@
+//  Copyright (c) 2013 Couchbase, Inc.
+
+package builtin
+
+import "fmt"
+
+import "github.com/prataprc/monster/common"
+
+var hostRoles = []string{
+	"web", "api", "db", "cache", "queue", "worker", "proxy", "search",
+}
+var hostEnvs = []string{"prod", "staging", "dev", "qa"}
+var hostRegions = []string{
+	"us-east", "us-west", "eu-west", "eu-central", "ap-south", "ap-northeast",
+}
+
+// Hostname will generate a hostname like `web-prod-03.us-east`,
+// combining a role, environment, index and region.
+func Hostname(scope common.Scope, args ...interface{}) interface{} {
+	rnd := scope.GetRandom()
+	return fmt.Sprintf("%s-%s-%02d.%s",
+		hostRoles[rnd.Intn(len(hostRoles))],
+		hostEnvs[rnd.Intn(len(hostEnvs))],
+		rnd.Intn(99)+1,
+		hostRegions[rnd.Intn(len(hostRegions))])
+}
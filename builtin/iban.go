@@ -0,0 +1,69 @@
+//  Copyright (c) 2013 Couchbase, Inc.
+
+package builtin
+
+import "fmt"
+import "sort"
+import "strings"
+
+import "github.com/prataprc/monster/common"
+
+// ibanFormats is the BBAN format for supported countries, where 'n'
+// is a digit and 'a' is an upper-case letter.
+var ibanFormats = map[string]string{
+	"BE": "nnnnnnnnnnnn",
+	"DE": "nnnnnnnnnnnnnnnnnn",
+	"ES": "nnnnnnnnnnnnnnnnnnnn",
+	"FR": "nnnnnnnnnnnnnnnnnnnnnnn",
+	"GB": "aaaannnnnnnnnnnnnn",
+	"IT": "annnnnnnnnnnnnnnnnnnnnn",
+	"NL": "aaaannnnnnnnnn",
+}
+
+// Iban will generate a random IBAN for country args[0], with valid
+// ISO 13616 mod-97 check digits.
+// args[0] - country code, one of BE, DE, ES, FR, GB, IT, NL.
+func Iban(scope common.Scope, args ...interface{}) interface{} {
+	if len(args) < 1 {
+		panic(fmt.Errorf("iban expects country code\n"))
+	}
+	country := strings.ToUpper(args[0].(string))
+	format, ok := ibanFormats[country]
+	if !ok {
+		countries := make([]string, 0, len(ibanFormats))
+		for c := range ibanFormats {
+			countries = append(countries, c)
+		}
+		sort.Strings(countries)
+		panic(fmt.Errorf("iban country %v not among %v\n", country, countries))
+	}
+	rnd := scope.GetRandom()
+	bban := make([]byte, len(format))
+	for i := range format {
+		if format[i] == 'a' {
+			bban[i] = byte('A' + rnd.Intn(26))
+		} else {
+			bban[i] = byte('0' + rnd.Intn(10))
+		}
+	}
+	check := 98 - IbanMod97(string(bban)+country+"00")
+	return fmt.Sprintf("%s%02d%s", country, check, bban)
+}
+
+// IbanMod97 will compute mod-97 of an alphanumeric string, where
+// letters are substituted by two digits, A=10 ... Z=35. An IBAN is
+// valid if IbanMod97(iban[4:] + iban[:4]) is 1.
+func IbanMod97(s string) int {
+	mod := 0
+	for _, ch := range s {
+		switch {
+		case ch >= '0' && ch <= '9':
+			mod = (mod*10 + int(ch-'0')) % 97
+		case ch >= 'A' && ch <= 'Z':
+			mod = (mod*100 + int(ch-'A') + 10) % 97
+		default:
+			panic(fmt.Errorf("invalid iban character %q in %v\n", ch, s))
+		}
+	}
+	return mod
+}
@@ -8,7 +8,18 @@ import "github.com/prataprc/monster/common"
 
 var _ = fmt.Sprintf("dummy")
 
-// Inc will increment a variable.
+// Counter is the value returned by `inc` and `dec`. It renders as an
+// empty string, so that stepping a counter within a rule adds nothing
+// to the generated text, while forms like `mod` can use its value.
+type Counter int64
+
+// String implement fmt.Stringer interface.
+func (c Counter) String() string {
+	return ""
+}
+
+// Inc will increment a variable and return its new value as Counter,
+// or empty string if the variable is not defined.
 // args[0] - variable name
 // args[1] - quantum of value to increment
 // if variable name is present in local scope it will be used,
@@ -21,6 +32,7 @@ func Inc(scope common.Scope, args ...interface{}) interface{} {
 	value, g, ok := scope.Get(name)
 	if ok {
 		scope.Set(name, value.(int64)+by, g)
+		return Counter(value.(int64) + by)
 	}
 	return ""
 }
@@ -0,0 +1,64 @@
+//  Copyright (c) 2013 Couchbase, Inc.
+
+package builtin
+
+import "fmt"
+import "time"
+
+import "github.com/prataprc/monster/common"
+
+// Interval will randomly pick a start time, and an end time that is
+// between mingap and maxgap after the start. The end time is
+// remembered for the current iteration, to be returned by
+// `intervalend`, and the start time is returned in layout args[0].
+// args[0] - golang time layout, like time.RFC3339.
+// args[1] - minimum gap, as golang duration like "30m", or seconds.
+// args[2] - maximum gap, as golang duration like "2h", or seconds.
+// args[3] - optional, start of range in RFC3339 format.
+// args[4] - optional, end of range in RFC3339 format.
+func Interval(scope common.Scope, args ...interface{}) interface{} {
+	if len(args) < 3 {
+		panic(fmt.Errorf("interval expects layout, mingap and maxgap\n"))
+	}
+	layout, from, till := args[0].(string), DefaultBusinessFrom, DefaultBusinessTill
+	mingap, maxgap := toDuration(args[1]), toDuration(args[2])
+	if mingap < 0 || maxgap < mingap {
+		panic(fmt.Errorf("interval invalid gap %v-%v\n", mingap, maxgap))
+	}
+	if len(args) > 4 {
+		from, till = args[3].(string), args[4].(string)
+	}
+	start, err := time.Parse(time.RFC3339, from)
+	if err != nil {
+		panic(fmt.Errorf("parsing start date %v: %v\n", from, err))
+	}
+	end, err := time.Parse(time.RFC3339, till)
+	if err != nil {
+		panic(fmt.Errorf("parsing end date %v: %v\n", till, err))
+	} else if !end.After(start) {
+		panic(fmt.Errorf("end %v is not after start %v\n", till, from))
+	}
+
+	rnd := scope.GetRandom()
+	t := start.Add(time.Duration(rnd.Int63n(int64(end.Sub(start)))))
+	gap := mingap
+	if maxgap > mingap {
+		gap += time.Duration(rnd.Int63n(int64(maxgap - mingap)))
+	}
+	scope.Set("interval:end", t.Add(gap), true /*global*/)
+	return t.Format(layout)
+}
+
+// Intervalend will return the end time picked by the last `interval`
+// in the current iteration, in layout args[0].
+// args[0] - golang time layout, like time.RFC3339.
+func Intervalend(scope common.Scope, args ...interface{}) interface{} {
+	if len(args) < 1 {
+		panic(fmt.Errorf("intervalend expects layout\n"))
+	}
+	end, _, ok := scope.Get("interval:end")
+	if !ok {
+		panic(fmt.Errorf("intervalend expects a preceding interval\n"))
+	}
+	return end.(time.Time).Format(args[0].(string))
+}
@@ -0,0 +1,41 @@
+//  Copyright (c) 2013 Couchbase, Inc.
+
+package builtin
+
+import "fmt"
+
+import "github.com/prataprc/monster/common"
+
+// Invcdf will sample from an empirical distribution described by
+// quantile/value pairs, by drawing a uniform quantile and linearly
+// interpolating between the surrounding pairs. Draws below the first
+// or above the last quantile are clamped to its value.
+// args[0], args[2] ... - quantile, increasing within [0,1].
+// args[1], args[3] ... - value at that quantile.
+func Invcdf(scope common.Scope, args ...interface{}) interface{} {
+	if len(args) < 4 || len(args)%2 != 0 {
+		panic(fmt.Errorf("invcdf expects atleast two quantile/value pairs\n"))
+	}
+	qs := make([]float64, 0, len(args)/2)
+	vs := make([]float64, 0, len(args)/2)
+	for i := 0; i < len(args); i += 2 {
+		q := toFloat64(args[i])
+		if q < 0 || q > 1 {
+			panic(fmt.Errorf("invcdf quantile %v not in [0,1]\n", q))
+		} else if len(qs) > 0 && q <= qs[len(qs)-1] {
+			panic(fmt.Errorf("invcdf quantile %v not increasing\n", q))
+		}
+		qs, vs = append(qs, q), append(vs, toFloat64(args[i+1]))
+	}
+	u := scope.GetRandom().Float64()
+	if u <= qs[0] {
+		return vs[0]
+	}
+	for i := 1; i < len(qs); i++ {
+		if u <= qs[i] {
+			frac := (u - qs[i-1]) / (qs[i] - qs[i-1])
+			return vs[i-1] + frac*(vs[i]-vs[i-1])
+		}
+	}
+	return vs[len(vs)-1]
+}
@@ -0,0 +1,108 @@
+//  Copyright (c) 2013 Couchbase, Inc.
+
+package builtin
+
+import "fmt"
+import "encoding/json"
+import "sort"
+
+import "github.com/prataprc/monster/common"
+
+// jsonObject is a parsed JSON object bag, keys are kept sorted so
+// that picks are reproducible for the same seed.
+type jsonObject struct {
+	keys  []string
+	items map[string]interface{}
+}
+
+// Jbag will fetch a random element from a JSON array file. If key
+// is supplied, array elements are expected to be objects and the
+// value for key in a random element is returned. String values are
+// returned as is, other values are returned in JSON format. Files
+// are read like other bags, from URL or gzip compressed.
+// args[0] - filename.
+// args[1] - optional, key into array of objects.
+func Jbag(scope common.Scope, args ...interface{}) interface{} {
+	filename := bagPath(scope, args[0].(string))
+	items := bagDerived("jbag:"+filename, func() interface{} {
+		return readJbagArray(filename, bagData(scope, filename))
+	}, bagEntryOf(scope, filename, true)).([]interface{})
+	if len(items) == 0 {
+		return ""
+	}
+	val := items[scope.GetRandom().Intn(len(items))]
+	if len(args) > 1 {
+		obj, ok := val.(map[string]interface{})
+		if !ok {
+			panic(fmt.Errorf("jbag expects array of objects in %v\n", filename))
+		}
+		val = obj[args[1].(string)]
+	}
+	return jsonValue(val)
+}
+
+// Jbagkey will fetch a random top-level key from a JSON object file.
+// args[0] - filename.
+func Jbagkey(scope common.Scope, args ...interface{}) interface{} {
+	obj := jbagObject(scope, args[0].(string))
+	if len(obj.keys) == 0 {
+		return ""
+	}
+	return obj.keys[scope.GetRandom().Intn(len(obj.keys))]
+}
+
+// Jbagval will fetch a random top-level value from a JSON object
+// file. String values are returned as is, other values are returned
+// in JSON format.
+// args[0] - filename.
+func Jbagval(scope common.Scope, args ...interface{}) interface{} {
+	obj := jbagObject(scope, args[0].(string))
+	if len(obj.keys) == 0 {
+		return ""
+	}
+	val := obj.items[obj.keys[scope.GetRandom().Intn(len(obj.keys))]]
+	return jsonValue(val)
+}
+
+// jsonValue will return string values as is, and other values in
+// JSON format.
+func jsonValue(val interface{}) string {
+	if s, ok := val.(string); ok {
+		return s
+	}
+	data, err := json.Marshal(val)
+	if err != nil {
+		panic(fmt.Errorf("unable to marshal %v: %v\n", val, err))
+	}
+	return string(data)
+}
+
+func jbagObject(scope common.Scope, filename string) *jsonObject {
+	filename = bagPath(scope, filename)
+	return bagDerived("jbagobj:"+filename, func() interface{} {
+		return readJbagObject(filename, bagData(scope, filename))
+	}, bagEntryOf(scope, filename, true)).(*jsonObject)
+}
+
+func readJbagObject(filename string, data []byte) *jsonObject {
+	obj := &jsonObject{items: make(map[string]interface{})}
+	if err := json.Unmarshal(data, &obj.items); err != nil {
+		fmsg := "unable to read file %q as JSON object: %v\n"
+		panic(fmt.Errorf(fmsg, filename, err))
+	}
+	obj.keys = make([]string, 0, len(obj.items))
+	for key := range obj.items {
+		obj.keys = append(obj.keys, key)
+	}
+	sort.Strings(obj.keys)
+	return obj
+}
+
+func readJbagArray(filename string, data []byte) []interface{} {
+	var items []interface{}
+	if err := json.Unmarshal(data, &items); err != nil {
+		fmsg := "unable to read file %q as JSON array: %v\n"
+		panic(fmt.Errorf(fmsg, filename, err))
+	}
+	return items
+}
@@ -0,0 +1,26 @@
+//  Copyright (c) 2013 Couchbase, Inc.
+
+package builtin
+
+import "fmt"
+import "strings"
+
+import "github.com/prataprc/monster/common"
+
+// Localebag will substitute `{locale}` in filename with the value of
+// variable `locale`, and fetch a random line from that file.
+// args[0] - filename, like "names_{locale}.csv".
+func Localebag(scope common.Scope, args ...interface{}) interface{} {
+	if len(args) < 1 {
+		panic(fmt.Errorf("localebag expects filename\n"))
+	}
+	filename := args[0].(string)
+	if strings.Contains(filename, "{locale}") {
+		locale, _, ok := scope.GetString("locale")
+		if !ok {
+			panic(fmt.Errorf("localebag expects variable `locale` for %v\n", filename))
+		}
+		filename = strings.Replace(filename, "{locale}", locale, -1)
+	}
+	return Bag(scope, filename)
+}
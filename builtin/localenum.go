@@ -0,0 +1,45 @@
+//  Copyright (c) 2013 Couchbase, Inc.
+
+package builtin
+
+import "fmt"
+import "strings"
+
+import "github.com/prataprc/monster/common"
+
+// Localenum will randomly pick one of the enumerated codes, store
+// the code as `$localenum` in local scope and return its label for
+// the locale in variable args[0]. If the code has no label for the
+// locale, its first label is returned, and if it has no labels at all
+// the code itself is returned.
+// args[0] - name of the variable holding the locale, like "en".
+// args[1] ... args[N] - code followed by its "<locale>:<label>"s.
+func Localenum(scope common.Scope, args ...interface{}) interface{} {
+	if len(args) < 2 {
+		panic(fmt.Errorf("localenum expects locale variable and codes\n"))
+	}
+	codes, labels := make([]string, 0), make([][]string, 0)
+	for _, arg := range args[1:] {
+		s := fmt.Sprintf("%v", arg)
+		if strings.Contains(s, ":") && len(codes) > 0 {
+			labels[len(labels)-1] = append(labels[len(labels)-1], s)
+		} else {
+			codes, labels = append(codes, s), append(labels, []string{})
+		}
+	}
+
+	i := scope.GetRandom().Intn(len(codes))
+	scope.Set("localenum", codes[i], false /*global*/)
+	if locale, _, ok := scope.GetString(args[0].(string)); ok {
+		for _, label := range labels[i] {
+			if strings.HasPrefix(label, locale+":") {
+				return label[len(locale)+1:]
+			}
+		}
+	}
+	if len(labels[i]) > 0 {
+		label := labels[i][0]
+		return label[strings.Index(label, ":")+1:]
+	}
+	return codes[i]
+}
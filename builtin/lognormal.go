@@ -0,0 +1,30 @@
+//  Copyright (c) 2013 Couchbase, Inc.
+
+package builtin
+
+import "fmt"
+import "math"
+
+import "github.com/prataprc/monster/common"
+
+var _ = fmt.Sprintf("dummy")
+
+// Lognormal will return a float64 sampled from a log-normal
+// distribution.
+// args[0] - mu, mean of the underlying normal distribution
+// args[1] - sigma, standard deviation of the underlying normal
+// args[2] - optional, upper bound to clamp the sample to
+func Lognormal(scope common.Scope, args ...interface{}) interface{} {
+	if len(args) < 2 {
+		panic(fmt.Errorf("lognormal expects mu and sigma\n"))
+	}
+	mu, sigma := toFloat64(args[0]), toFloat64(args[1])
+	if sigma < 0 {
+		panic(fmt.Errorf("lognormal sigma %v cannot be negative\n", sigma))
+	}
+	f := math.Exp(scope.GetRandom().NormFloat64()*sigma + mu)
+	if len(args) > 2 {
+		f = math.Min(f, toFloat64(args[2]))
+	}
+	return f
+}
@@ -0,0 +1,48 @@
+//  Copyright (c) 2013 Couchbase, Inc.
+
+package builtin
+
+import "fmt"
+import "math"
+
+import "github.com/prataprc/monster/common"
+
+// Min will return the smallest of its arguments. If any of the
+// arguments is float64 the result is float64, else int64.
+func Min(scope common.Scope, args ...interface{}) interface{} {
+	return minmax("min", args,
+		func(a, b int64) bool { return a < b }, math.Min)
+}
+
+// Max will return the largest of its arguments. If any of the
+// arguments is float64 the result is float64, else int64.
+func Max(scope common.Scope, args ...interface{}) interface{} {
+	return minmax("max", args,
+		func(a, b int64) bool { return a > b }, math.Max)
+}
+
+func minmax(
+	name string, args []interface{},
+	intless func(a, b int64) bool,
+	floatop func(a, b float64) float64) interface{} {
+
+	if len(args) < 1 {
+		panic(fmt.Errorf("%v expects atleast one argument\n", name))
+	} else if len(args) == 1 {
+		return args[0]
+	}
+	if isInts(args) {
+		acc := args[0].(int64)
+		for _, arg := range args[1:] {
+			if val := arg.(int64); intless(val, acc) {
+				acc = val
+			}
+		}
+		return acc
+	}
+	acc := toFloat64(args[0])
+	for _, arg := range args[1:] {
+		acc = floatop(acc, toFloat64(arg))
+	}
+	return acc
+}
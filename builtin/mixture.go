@@ -0,0 +1,31 @@
+//  Copyright (c) 2013 Couchbase, Inc.
+
+package builtin
+
+import "fmt"
+
+import "github.com/prataprc/monster/common"
+
+// Mixture will pick a normal distribution based on its weight and
+// return a float64 sampled from it.
+// args[0], args[3] ... - weight of the component, must be positive.
+// args[1], args[4] ... - mean of the component.
+// args[2], args[5] ... - standard deviation of the component.
+func Mixture(scope common.Scope, args ...interface{}) interface{} {
+	if len(args) < 3 || len(args)%3 != 0 {
+		panic(fmt.Errorf("mixture expects weight/mean/sd groups\n"))
+	}
+	weights := make([]float64, 0, len(args)/3)
+	for i := 0; i < len(args); i += 3 {
+		w, sd := toFloat64(args[i]), toFloat64(args[i+2])
+		if w <= 0 {
+			panic(fmt.Errorf("mixture weight %v must be positive\n", w))
+		} else if sd < 0 {
+			panic(fmt.Errorf("mixture sd %v cannot be negative\n", sd))
+		}
+		weights = append(weights, w)
+	}
+	rnd := scope.GetRandom()
+	i := pickWeighted(rnd, weights) * 3
+	return rnd.NormFloat64()*toFloat64(args[i+2]) + toFloat64(args[i+1])
+}
@@ -0,0 +1,29 @@
+//  Copyright (c) 2013 Couchbase, Inc.
+
+package builtin
+
+import "fmt"
+
+import "github.com/prataprc/monster/common"
+
+// Mod will return args[0] % args[1], useful to wrap a counter into
+// buckets, like (mod (inc counter) 10). Both arguments shall evaluate
+// to int64, or Counter returned by `inc` and `dec`.
+// args[0] - dividend
+// args[1] - modulus, must be non-zero.
+func Mod(scope common.Scope, args ...interface{}) interface{} {
+	if len(args) < 2 {
+		panic(fmt.Errorf("mod expects two arguments\n"))
+	}
+	a, ok1 := args[0].(int64)
+	b, ok2 := args[1].(int64)
+	if c, ok := args[0].(Counter); ok {
+		a, ok1 = int64(c), true
+	}
+	if !ok1 || !ok2 {
+		panic(fmt.Errorf("mod expects int64 arguments, got %v\n", args))
+	} else if b == 0 {
+		panic(fmt.Errorf("mod by zero for %v\n", a))
+	}
+	return a % b
+}
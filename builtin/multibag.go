@@ -0,0 +1,27 @@
+//  Copyright (c) 2013 Couchbase, Inc.
+
+package builtin
+
+import "fmt"
+
+import "github.com/prataprc/monster/common"
+
+// Multibag will pick a file based on its weight and fetch a random
+// line from it.
+// args[0], args[2] ... args[N-1] - weight of file, must be positive.
+// args[1], args[3] ... args[N] - filename.
+func Multibag(scope common.Scope, args ...interface{}) interface{} {
+	if len(args) < 2 || len(args)%2 != 0 {
+		panic(fmt.Errorf("multibag expects weight/filename pairs\n"))
+	}
+	weights := make([]float64, 0, len(args)/2)
+	for i := 0; i < len(args); i += 2 {
+		w := toFloat64(args[i])
+		if w <= 0 {
+			panic(fmt.Errorf("multibag weight %v must be positive\n", w))
+		}
+		weights = append(weights, w)
+	}
+	i := pickWeighted(scope.GetRandom(), weights) * 2
+	return Bag(scope, args[i+1])
+}
@@ -0,0 +1,34 @@
+//  Copyright (c) 2013 Couchbase, Inc.
+
+package builtin
+
+import "fmt"
+
+import "github.com/prataprc/monster/common"
+
+// LetterFrequencies are relative frequencies, in percent, of letters
+// 'a' to 'z' in English text.
+var LetterFrequencies = []float64{
+	8.167, 1.492, 2.782, 4.253, 12.702, 2.228, 2.015, 6.094, 6.966,
+	0.153, 0.772, 4.025, 2.406, 6.749, 7.507, 1.929, 0.095, 5.987,
+	6.327, 9.056, 2.758, 0.978, 2.360, 0.150, 1.974, 0.074,
+}
+
+// Natstr will generate a random string of length args[0], with
+// letters sampled as per their frequency in English text.
+// args[0] - length of the string.
+func Natstr(scope common.Scope, args ...interface{}) interface{} {
+	if len(args) < 1 {
+		panic(fmt.Errorf("natstr expects length\n"))
+	}
+	n := toInt64(args[0])
+	if n < 0 {
+		panic(fmt.Errorf("natstr length %v cannot be negative\n", n))
+	}
+	rnd := scope.GetRandom()
+	str := make([]byte, n)
+	for i := range str {
+		str[i] = byte('a' + pickWeighted(rnd, LetterFrequencies))
+	}
+	return string(str)
+}
@@ -0,0 +1,41 @@
+//  Copyright (c) 2013 Couchbase, Inc.
+
+package builtin
+
+import "fmt"
+import "encoding/json"
+
+import "github.com/prataprc/monster/common"
+
+// MaxNestedDepth is the maximum depth allowed for `nested` form.
+const MaxNestedDepth = 64
+
+// Nested will generate a JSON object nested `depth` levels deep,
+// each level containing a "leaf" value and a "child" object, except
+// the innermost level which contains only the "leaf". Nested is a
+// lazy form, the leaf form is evaluated once for every level.
+// args[0] - depth, between 1 and MaxNestedDepth.
+// args[1] - form to generate the leaf value.
+func Nested(scope common.Scope, args ...interface{}) interface{} {
+	if len(args) < 2 {
+		panic(fmt.Errorf("nested expects depth and leaf form\n"))
+	}
+	depth := toInt64(args[0].(*common.Form).Eval(scope))
+	if depth < 1 || depth > MaxNestedDepth {
+		panic(fmt.Errorf("nested depth %v not in 1..%v\n", depth, MaxNestedDepth))
+	}
+	leafform := args[1].(*common.Form)
+	var obj map[string]interface{}
+	for i := int64(0); i < depth; i++ {
+		level := map[string]interface{}{"leaf": leafform.Eval(scope)}
+		if obj != nil {
+			level["child"] = obj
+		}
+		obj = level
+	}
+	data, err := json.Marshal(obj)
+	if err != nil {
+		panic(fmt.Errorf("unable to marshal nested object: %v\n", err))
+	}
+	return string(data)
+}
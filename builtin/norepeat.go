@@ -0,0 +1,36 @@
+//  Copyright (c) 2013 Couchbase, Inc.
+
+package builtin
+
+import "fmt"
+import "strings"
+
+import "github.com/prataprc/monster/common"
+
+// Norepeat will randomly pick one of the passed argument, but never
+// the same value picked by its previous evaluation within a run.
+// The last pick is remembered in global scope, and compared by its
+// string form, so that arguments like lists can be picked.
+func Norepeat(scope common.Scope, args ...interface{}) interface{} {
+	if len(args) < 1 {
+		panic(fmt.Errorf("insufficient arguments to norepeat\n"))
+	}
+	keys := make([]string, 0, len(args))
+	for _, arg := range args {
+		keys = append(keys, fmt.Sprintf("%q", fmt.Sprint(arg)))
+	}
+	name := "_norepeat:" + strings.Join(keys, ",")
+	last, _, ok := scope.Get(name)
+	choices := make([]int, 0, len(args))
+	for i := range args {
+		if !ok || keys[i] != last {
+			choices = append(choices, i)
+		}
+	}
+	if len(choices) == 0 { // single option, nothing else to pick.
+		return args[0]
+	}
+	i := choices[scope.GetRandom().Intn(len(choices))]
+	scope.Set(name, keys[i], true /*global*/)
+	return args[i]
+}
@@ -0,0 +1,24 @@
+//  Copyright (c) 2013 Couchbase, Inc.
+
+package builtin
+
+import "fmt"
+import "math"
+
+import "github.com/prataprc/monster/common"
+
+// Pareto will return a float64 sampled from a Pareto distribution
+// using inverse transform sampling.
+// args[0] - xm, minimum value, must be positive.
+// args[1] - alpha, shape of the tail, must be positive.
+func Pareto(scope common.Scope, args ...interface{}) interface{} {
+	if len(args) < 2 {
+		panic(fmt.Errorf("pareto expects xm and alpha\n"))
+	}
+	xm, alpha := toFloat64(args[0]), toFloat64(args[1])
+	if xm <= 0 || alpha <= 0 {
+		panic(fmt.Errorf("pareto expects positive xm %v and alpha %v\n", xm, alpha))
+	}
+	u := 1.0 - scope.GetRandom().Float64() // (0, 1]
+	return xm / math.Pow(u, 1/alpha)
+}
@@ -0,0 +1,39 @@
+//  Copyright (c) 2013 Couchbase, Inc.
+
+package builtin
+
+import "fmt"
+import "sort"
+import "encoding/json"
+
+import "github.com/prataprc/monster/common"
+
+// Partition will randomly split args[0] into args[1] non-negative
+// integers that sum up to args[0], and return them as JSON array.
+// args[0] - total
+// args[1] - number of parts
+func Partition(scope common.Scope, args ...interface{}) interface{} {
+	if len(args) < 2 {
+		panic(fmt.Errorf("partition expects total and number of parts\n"))
+	}
+	total, n := toInt64(args[0]), toInt64(args[1])
+	if total < 0 || n < 1 {
+		panic(fmt.Errorf("partition invalid total %v or parts %v\n", total, n))
+	}
+	rnd := scope.GetRandom()
+	cuts := make([]int64, 0, n+1)
+	for i := int64(1); i < n; i++ {
+		cuts = append(cuts, rnd.Int63n(total+1))
+	}
+	cuts = append(cuts, 0, total)
+	sort.Slice(cuts, func(i, j int) bool { return cuts[i] < cuts[j] })
+	parts := make([]int64, 0, n)
+	for i := 1; i < len(cuts); i++ {
+		parts = append(parts, cuts[i]-cuts[i-1])
+	}
+	data, err := json.Marshal(parts)
+	if err != nil {
+		panic(fmt.Errorf("unable to marshal %v: %v\n", parts, err))
+	}
+	return string(data)
+}
@@ -0,0 +1,41 @@
+//  Copyright (c) 2013 Couchbase, Inc.
+
+package builtin
+
+import "fmt"
+
+import "github.com/prataprc/monster/common"
+
+var passwordClasses = []string{
+	"ABCDEFGHIJKLMNOPQRSTUVWXYZ",
+	"abcdefghijklmnopqrstuvwxyz",
+	"0123456789",
+	"!@#$%^&*()-_=+[]{};:,.?",
+}
+
+// Password will generate a random password of length args[0], with
+// atleast one upper-case letter, lower-case letter, digit and symbol.
+// args[0] - length of password, atleast 4.
+func Password(scope common.Scope, args ...interface{}) interface{} {
+	if len(args) < 1 {
+		panic(fmt.Errorf("password expects length\n"))
+	}
+	n := int(toInt64(args[0]))
+	if n < len(passwordClasses) {
+		panic(fmt.Errorf("password length %v less than %v\n", n, len(passwordClasses)))
+	}
+	rnd, all := scope.GetRandom(), ""
+	password := make([]byte, 0, n)
+	for _, class := range passwordClasses {
+		password = append(password, class[rnd.Intn(len(class))])
+		all += class
+	}
+	for len(password) < n {
+		password = append(password, all[rnd.Intn(len(all))])
+	}
+	for i := len(password) - 1; i > 0; i-- {
+		j := rnd.Intn(i + 1)
+		password[i], password[j] = password[j], password[i]
+	}
+	return string(password)
+}
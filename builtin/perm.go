@@ -0,0 +1,43 @@
+//  Copyright (c) 2013 Couchbase, Inc.
+
+package builtin
+
+import "fmt"
+import "encoding/json"
+
+import "github.com/prataprc/monster/common"
+
+// Perm will return a random permutation of integers [0,n) as JSON
+// array. When an index is passed, a permutation is generated once per
+// run and remembered in global scope, and its i-th element is
+// returned as int64.
+// args[0] - n
+// args[1] - optional, index into the run's fixed permutation.
+func Perm(scope common.Scope, args ...interface{}) interface{} {
+	if len(args) < 1 {
+		panic(fmt.Errorf("perm expects n\n"))
+	}
+	n := toInt64(args[0])
+	if n < 0 {
+		panic(fmt.Errorf("perm expects non-negative n, got %v\n", n))
+	}
+	if len(args) == 1 {
+		data, err := json.Marshal(scope.GetRandom().Perm(int(n)))
+		if err != nil {
+			panic(fmt.Errorf("unable to marshal permutation: %v\n", err))
+		}
+		return string(data)
+	}
+
+	name := fmt.Sprintf("_perm:%v", n)
+	perm, _, ok := scope.Get(name)
+	if !ok {
+		perm = scope.GetRandom().Perm(int(n))
+		scope.Set(name, perm, true /*global*/)
+	}
+	i := toInt64(args[1])
+	if i < 0 || i >= n {
+		panic(fmt.Errorf("perm index %v out of range [0,%v)\n", i, n))
+	}
+	return int64(perm.([]int)[i])
+}
@@ -0,0 +1,33 @@
+//  Copyright (c) 2013 Couchbase, Inc.
+
+package builtin
+
+import "fmt"
+
+import "github.com/prataprc/monster/common"
+
+var _ = fmt.Sprintf("dummy")
+
+// Pipe will evaluate args[0] and feed its value through each of the
+// remaining forms, binding the intermediate value as `$_` in local
+// scope. Previous binding of `$_`, if any, is restored on return, so
+// that pipes can be nested. Pipe is a lazy form, its arguments are
+// un-evaluated forms.
+// args[0] - form to generate the initial value
+// args[1] ... args[N] - forms transforming `$_`
+func Pipe(scope common.Scope, args ...interface{}) interface{} {
+	if len(args) < 1 {
+		panic(fmt.Errorf("insufficient arguments to pipe\n"))
+	}
+	if prev, ok := scope["_"]; ok {
+		defer scope.Set("_", prev, false /*global*/)
+	} else {
+		defer scope.Del("_", nil, false /*global*/)
+	}
+	val := args[0].(*common.Form).Eval(scope)
+	for _, arg := range args[1:] {
+		scope.Set("_", val, false /*global*/)
+		val = arg.(*common.Form).Eval(scope)
+	}
+	return val
+}
@@ -0,0 +1,26 @@
+//  Copyright (c) 2013 Couchbase, Inc.
+
+package builtin
+
+import "fmt"
+
+import "github.com/prataprc/monster/common"
+
+// Progression will return `start + idx*step`, with a uniformly random
+// noise in [-jitter, jitter] added to it, as float64.
+// args[0] - idx, integer or name of an integer variable.
+// args[1] - start
+// args[2] - step
+// args[3] - jitter
+func Progression(scope common.Scope, args ...interface{}) interface{} {
+	if len(args) < 4 {
+		panic(fmt.Errorf("progression expects idxvar, start, step and jitter\n"))
+	}
+	idx := varInt64(scope, args[0])
+	start, step, jitter := toFloat64(args[1]), toFloat64(args[2]), toFloat64(args[3])
+	if jitter < 0 {
+		panic(fmt.Errorf("progression jitter %v cannot be negative\n", jitter))
+	}
+	noise := (scope.GetRandom().Float64()*2 - 1) * jitter
+	return start + float64(idx)*step + noise
+}
@@ -0,0 +1,38 @@
+//  Copyright (c) 2013 Couchbase, Inc.
+
+package builtin
+
+import "fmt"
+import "strconv"
+import "strings"
+
+import "github.com/prataprc/monster/common"
+
+// ProtoText is a message rendered in protobuf text format, nested
+// `prototext` forms are rendered as nested messages.
+type ProtoText string
+
+// Prototext will render field/value pairs in protobuf text format,
+// one `field: value` per line. String values are quoted, and values
+// generated by a nested `prototext` are rendered as nested messages.
+// args[0], args[2] ... args[N-1] - field name
+// args[1], args[3] ... args[N] - field value
+func Prototext(scope common.Scope, args ...interface{}) interface{} {
+	if len(args)%2 != 0 {
+		panic(fmt.Errorf("prototext expects field/value pairs\n"))
+	}
+	lines := make([]string, 0, len(args)/2)
+	for i := 0; i < len(args); i += 2 {
+		field := fmt.Sprintf("%v", args[i])
+		switch val := args[i+1].(type) {
+		case ProtoText:
+			nested := strings.Replace(string(val), "\n", "\n  ", -1)
+			lines = append(lines, fmt.Sprintf("%s {\n  %s\n}", field, nested))
+		case string:
+			lines = append(lines, fmt.Sprintf("%s: %s", field, strconv.Quote(val)))
+		default:
+			lines = append(lines, fmt.Sprintf("%s: %v", field, val))
+		}
+	}
+	return ProtoText(strings.Join(lines, "\n"))
+}
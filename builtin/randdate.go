@@ -0,0 +1,48 @@
+//  Copyright (c) 2013 Couchbase, Inc.
+
+package builtin
+
+import "fmt"
+import "time"
+
+import "github.com/prataprc/monster/common"
+
+// DefaultDateLayout is the default layout for `randdate`.
+const DefaultDateLayout = "2006-01-02"
+
+// Randdate will pick a uniformly random instant between start and
+// end dates and return it formatted using layout.
+// args[0] - start date in RFC3339 format.
+// args[1] - end date in RFC3339 format.
+// args[2] - optional, golang time layout, defaults to "2006-01-02".
+func Randdate(scope common.Scope, args ...interface{}) interface{} {
+	if len(args) < 2 {
+		panic(fmt.Errorf("randdate expects start and end dates\n"))
+	}
+	from, till, layout := args[0].(string), args[1].(string), DefaultDateLayout
+	if len(args) > 2 {
+		layout = args[2].(string)
+	}
+	t := randInstant(scope, "randdate", from, till)
+	return t.Format(layout)
+}
+
+// randInstant will pick a uniformly random instant between RFC3339
+// dates `from` and `till`, both inclusive.
+func randInstant(scope common.Scope, name, from, till string) time.Time {
+	start, err := time.Parse(time.RFC3339, from)
+	if err != nil {
+		panic(fmt.Errorf("parsing start date %v: %v\n", from, err))
+	}
+	end, err := time.Parse(time.RFC3339, till)
+	if err != nil {
+		panic(fmt.Errorf("parsing end date %v: %v\n", till, err))
+	}
+	if start.After(end) {
+		panic(fmt.Errorf("%v start %v is after end %v\n", name, from, till))
+	}
+	if d := int64(end.Sub(start)); d > 0 {
+		return start.Add(time.Duration(scope.GetRandom().Int63n(d + 1)))
+	}
+	return start
+}
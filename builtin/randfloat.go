@@ -0,0 +1,34 @@
+//  Copyright (c) 2013 Couchbase, Inc.
+
+package builtin
+
+import "fmt"
+import "math"
+
+import "github.com/prataprc/monster/common"
+
+// Randfloat will randomly pick a float64 between args[0] and args[1],
+// rounded half away from zero to args[2] decimal places.
+// args[0] - minimum value
+// args[1] - maximum value
+// args[2] - optional, number of decimal places, defaults to 2.
+func Randfloat(scope common.Scope, args ...interface{}) interface{} {
+	if len(args) < 2 {
+		panic(fmt.Errorf("randfloat expects min and max\n"))
+	}
+	min, max, decimals := toFloat64(args[0]), toFloat64(args[1]), int64(2)
+	if len(args) > 2 {
+		decimals = toInt64(args[2])
+	}
+	if min > max || decimals < 0 {
+		panic(fmt.Errorf("randfloat invalid range %v-%v or decimals %v\n", min, max, decimals))
+	}
+	p := math.Pow(10, float64(decimals))
+	f := math.Round((min+scope.GetRandom().Float64()*(max-min))*p) / p
+	if f > max { // rounding shall not cross the bounds.
+		f = math.Floor(max*p) / p
+	} else if f < min {
+		f = math.Ceil(min*p) / p
+	}
+	return f
+}
@@ -0,0 +1,43 @@
+//  Copyright (c) 2013 Couchbase, Inc.
+
+package builtin
+
+import "fmt"
+import "net"
+
+import "github.com/prataprc/monster/common"
+
+// IPv4 will generate a random IPv4 address in dotted-quad notation.
+// args[0] - optional, CIDR like "10.0.0.0/8" to constrain the
+// address to that network.
+func IPv4(scope common.Scope, args ...interface{}) interface{} {
+	rnd := scope.GetRandom()
+	ip := make(net.IP, net.IPv4len)
+	for i := range ip {
+		ip[i] = byte(rnd.Intn(256))
+	}
+	if len(args) > 0 {
+		_, network, err := net.ParseCIDR(args[0].(string))
+		if err != nil {
+			panic(fmt.Errorf("randipv4 parsing cidr %v: %v\n", args[0], err))
+		}
+		base := network.IP.To4()
+		if base == nil {
+			panic(fmt.Errorf("randipv4 expects an IPv4 cidr, got %v\n", args[0]))
+		}
+		for i := range ip {
+			ip[i] = base[i] | (ip[i] &^ network.Mask[i])
+		}
+	}
+	return ip.String()
+}
+
+// IPv6 will generate a random IPv6 address in colon-hex notation.
+func IPv6(scope common.Scope, args ...interface{}) interface{} {
+	rnd := scope.GetRandom()
+	hextets := make([]interface{}, 8)
+	for i := range hextets {
+		hextets[i] = rnd.Intn(0x10000)
+	}
+	return fmt.Sprintf("%x:%x:%x:%x:%x:%x:%x:%x", hextets...)
+}
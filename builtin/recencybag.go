@@ -0,0 +1,43 @@
+//  Copyright (c) 2013 Couchbase, Inc.
+
+package builtin
+
+import "fmt"
+import "math"
+
+import "github.com/prataprc/monster/common"
+
+// Recencybag will fetch a random value from a bag, where later
+// records are more likely. Weight of a record halves for every
+// `halflife` records preceding the last record. Weights are cached
+// as an alias table.
+// args[0] - filename.
+// args[1] - column index of value.
+// args[2] - halflife, in number of records.
+func Recencybag(scope common.Scope, args ...interface{}) interface{} {
+	if len(args) < 3 {
+		panic(fmt.Errorf("recencybag expects filename, valcol and halflife\n"))
+	}
+	filename := bagPath(scope, args[0].(string))
+	valcol, halflife := toInt64(args[1]), toFloat64(args[2])
+	if halflife <= 0 {
+		panic(fmt.Errorf("recencybag halflife %v must be positive\n", halflife))
+	}
+	key := fmt.Sprintf("recencybag:%v:%v:%v", filename, valcol, halflife)
+	table := bagDerived(key, func() interface{} {
+		records := bagRecords(scope, filename)
+		values := make([]string, 0, len(records))
+		weights := make([]float64, 0, len(records))
+		for i, record := range records {
+			if valcol >= int64(len(record)) {
+				fmsg := "recencybag column out of range in %v: %v\n"
+				panic(fmt.Errorf(fmsg, filename, record))
+			}
+			age := float64(len(records) - 1 - i)
+			values = append(values, record[valcol])
+			weights = append(weights, math.Pow(0.5, age/halflife))
+		}
+		return newAliasTable(values, weights)
+	}, bagEntryOf(scope, filename, false)).(*aliasTable)
+	return table.pick(scope.GetRandom())
+}
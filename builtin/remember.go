@@ -0,0 +1,34 @@
+//  Copyright (c) 2013 Couchbase, Inc.
+
+package builtin
+
+import "fmt"
+
+import "github.com/prataprc/monster/common"
+
+// Remember will store the value in args[1] under name args[0] for
+// the current iteration, and return the value. Remembered values are
+// kept in global scope, hence reset on RebuildContext().
+// args[0] - name
+// args[1] - value
+func Remember(scope common.Scope, args ...interface{}) interface{} {
+	if len(args) < 2 {
+		panic(fmt.Errorf("remember expects name and value\n"))
+	}
+	scope.Set("remember:"+args[0].(string), args[1], true /*global*/)
+	return args[1]
+}
+
+// Recall will return the value remembered under name args[0] in the
+// current iteration.
+// args[0] - name
+func Recall(scope common.Scope, args ...interface{}) interface{} {
+	if len(args) < 1 {
+		panic(fmt.Errorf("recall expects name\n"))
+	}
+	val, _, ok := scope.Get("remember:" + args[0].(string))
+	if !ok {
+		panic(fmt.Errorf("recall %q not remembered\n", args[0]))
+	}
+	return val
+}
@@ -0,0 +1,23 @@
+//  Copyright (c) 2013 Couchbase, Inc.
+
+package builtin
+
+import "fmt"
+import "strings"
+
+import "github.com/prataprc/monster/common"
+
+// Repeat will return args[0] concatenated args[1] times, negative
+// count is treated as zero.
+// args[0] - string
+// args[1] - count
+func Repeat(scope common.Scope, args ...interface{}) interface{} {
+	if len(args) < 2 {
+		panic(fmt.Errorf("repeat expects string and count\n"))
+	}
+	count := toInt64(args[1])
+	if count < 0 {
+		count = 0
+	}
+	return strings.Repeat(fmt.Sprintf("%v", args[0]), int(count))
+}
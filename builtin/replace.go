@@ -0,0 +1,30 @@
+//  Copyright (c) 2013 Couchbase, Inc.
+
+package builtin
+
+import "fmt"
+import "strings"
+
+import "github.com/prataprc/monster/common"
+
+// Replace will replace occurences of args[1] in args[0] with args[2].
+// args[0] - source string
+// args[1] - old sub-string, if empty source is returned unchanged.
+// args[2] - new sub-string
+// args[3] - optional, number of occurences to replace, defaults to
+// -1 to replace all.
+func Replace(scope common.Scope, args ...interface{}) interface{} {
+	if len(args) < 3 {
+		panic(fmt.Errorf("replace expects source, old and new\n"))
+	}
+	src := fmt.Sprintf("%v", args[0])
+	old, repl := fmt.Sprintf("%v", args[1]), fmt.Sprintf("%v", args[2])
+	n := int64(-1)
+	if len(args) > 3 {
+		n = toInt64(args[3])
+	}
+	if old == "" {
+		return src
+	}
+	return strings.Replace(src, old, repl, int(n))
+}
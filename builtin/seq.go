@@ -0,0 +1,33 @@
+//  Copyright (c) 2013 Couchbase, Inc.
+
+package builtin
+
+import "fmt"
+
+import "github.com/prataprc/monster/common"
+
+// Seq will return the current value of sequence `name` and advance
+// it by `step`. Sequence is maintained in global scope and continues
+// across RebuildContext(), hence across generated documents.
+// args[0] - name of the sequence.
+// args[1] - optional, start value, defaults to 0.
+// args[2] - optional, step value, defaults to 1.
+func Seq(scope common.Scope, args ...interface{}) interface{} {
+	if len(args) < 1 {
+		panic(fmt.Errorf("seq expects name\n"))
+	}
+	start, step := int64(0), int64(1)
+	if len(args) > 1 {
+		start = toInt64(args[1])
+	}
+	if len(args) > 2 {
+		step = toInt64(args[2])
+	}
+	name := "_seq:" + args[0].(string)
+	val := start
+	if curr, _, ok := scope.Get(name); ok {
+		val = curr.(int64)
+	}
+	scope.Set(name, val+step, true /*global*/)
+	return val
+}
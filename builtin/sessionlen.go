@@ -0,0 +1,38 @@
+//  Copyright (c) 2013 Couchbase, Inc.
+
+package builtin
+
+import "fmt"
+import "math"
+
+import "github.com/prataprc/monster/common"
+
+// DefaultSessionMedian and DefaultSessionMax are default median and
+// maximum session length, in seconds, for `sessionlen`.
+const DefaultSessionMedian = 120
+const DefaultSessionMax = 4 * 60 * 60
+
+// Sessionlen will return a session length in seconds as int64,
+// sampled from a log-normal distribution that is skewed towards short
+// sessions with a long tail, bounded between 1 and max.
+// args[0] - optional, median session length in seconds.
+// args[1] - optional, maximum session length in seconds.
+// args[2] - optional, sigma of the distribution, defaults to 1.0.
+func Sessionlen(scope common.Scope, args ...interface{}) interface{} {
+	median, max, sigma := float64(DefaultSessionMedian), float64(DefaultSessionMax), 1.0
+	if len(args) > 0 {
+		median = toFloat64(args[0])
+	}
+	if len(args) > 1 {
+		max = toFloat64(args[1])
+	}
+	if len(args) > 2 {
+		sigma = toFloat64(args[2])
+	}
+	if median < 1 || max < median || sigma < 0 {
+		fmsg := "sessionlen invalid median %v, max %v or sigma %v\n"
+		panic(fmt.Errorf(fmsg, median, max, sigma))
+	}
+	f := math.Exp(scope.GetRandom().NormFloat64()*sigma + math.Log(median))
+	return int64(math.Max(1, math.Min(f, max)))
+}
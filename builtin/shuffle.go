@@ -0,0 +1,31 @@
+//  Copyright (c) 2013 Couchbase, Inc.
+
+package builtin
+
+import "fmt"
+import "strings"
+
+import "github.com/prataprc/monster/common"
+
+// Shuffle will return a random permutation of its arguments, joined
+// by a separator that defaults to ",".
+// args[0] - optional, "sep=<separator>" to set the separator.
+// args[0:] - items to shuffle.
+func Shuffle(scope common.Scope, args ...interface{}) interface{} {
+	sep := ","
+	if len(args) > 0 {
+		if s, ok := args[0].(string); ok && strings.HasPrefix(s, "sep=") {
+			sep, args = s[4:], args[1:]
+		}
+	}
+	items := make([]string, 0, len(args))
+	for _, arg := range args {
+		items = append(items, fmt.Sprintf("%v", arg))
+	}
+	rnd := scope.GetRandom()
+	for i := len(items) - 1; i > 0; i-- {
+		j := rnd.Intn(i + 1)
+		items[i], items[j] = items[j], items[i]
+	}
+	return strings.Join(items, sep)
+}
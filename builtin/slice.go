@@ -0,0 +1,54 @@
+//  Copyright (c) 2013 Couchbase, Inc.
+
+package builtin
+
+import "fmt"
+import "strings"
+
+import "github.com/prataprc/monster/common"
+
+// Index will return the element at args[1] in slice args[0].
+// args[0] - slice, like the one returned by `bagslice`.
+// args[1] - index, negative index counts from the end.
+func Index(scope common.Scope, args ...interface{}) interface{} {
+	if len(args) < 2 {
+		panic(fmt.Errorf("index expects slice and index\n"))
+	}
+	items, i := toSlice(args[0]), toInt64(args[1])
+	if i < 0 {
+		i += int64(len(items))
+	}
+	if i < 0 || i >= int64(len(items)) {
+		panic(fmt.Errorf("index %v out of range for %v\n", args[1], items))
+	}
+	return items[i]
+}
+
+// Join will concatenate elements of slice args[1] separated by args[0].
+// args[0] - separator
+// args[1] - slice, like the one returned by `bagslice`.
+func Join(scope common.Scope, args ...interface{}) interface{} {
+	if len(args) < 2 {
+		panic(fmt.Errorf("join expects separator and slice\n"))
+	}
+	items := toSlice(args[1])
+	strs := make([]string, 0, len(items))
+	for _, item := range items {
+		strs = append(strs, fmt.Sprintf("%v", item))
+	}
+	return strings.Join(strs, args[0].(string))
+}
+
+func toSlice(arg interface{}) []interface{} {
+	switch items := arg.(type) {
+	case []interface{}:
+		return items
+	case []string:
+		slice := make([]interface{}, 0, len(items))
+		for _, item := range items {
+			slice = append(slice, item)
+		}
+		return slice
+	}
+	panic(fmt.Errorf("expected slice, got %T(%v)\n", arg, arg))
+}
@@ -0,0 +1,32 @@
+//  Copyright (c) 2013 Couchbase, Inc.
+
+package builtin
+
+import "fmt"
+
+import "github.com/prataprc/monster/common"
+
+// Smoothbag will fetch a random value from a bag, weighted by the
+// observed count in another column of the same record, after adding
+// `alpha` to every count (additive smoothing).
+// args[0] - filename.
+// args[1] - column index of value.
+// args[2] - column index of count.
+// args[3] - alpha, smoothing added to each count.
+func Smoothbag(scope common.Scope, args ...interface{}) interface{} {
+	if len(args) < 4 {
+		panic(fmt.Errorf("smoothbag expects filename, valcol, countcol and alpha\n"))
+	}
+	filename := bagPath(scope, args[0].(string))
+	valcol, countcol := toInt64(args[1]), toInt64(args[2])
+	alpha := toFloat64(args[3])
+	if alpha < 0 {
+		panic(fmt.Errorf("smoothbag alpha %v cannot be negative\n", alpha))
+	}
+	values, counts := bagWeights(scope, filename, valcol, countcol)
+	weights := make([]float64, len(counts))
+	for i, count := range counts {
+		weights[i] = count + alpha
+	}
+	return values[pickWeighted(scope.GetRandom(), weights)]
+}
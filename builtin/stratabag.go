@@ -0,0 +1,79 @@
+//  Copyright (c) 2013 Couchbase, Inc.
+
+package builtin
+
+import "fmt"
+
+import "github.com/prataprc/monster/common"
+
+// strataTable cycles through strata, in proportion to the number
+// of records in each stratum.
+type strataTable struct {
+	values   [][]string // values in each stratum
+	schedule []int      // stratum for each slot in the cycle
+}
+
+// Stratabag will pick a stratum in round-robin based on the record
+// index, and return a random value from that stratum. Within a cycle
+// of `total` records each stratum is represented in proportion to
+// its number of records in the bag.
+// args[0] - filename.
+// args[1] - column index of stratum.
+// args[2] - column index of value.
+// args[3] - idx, integer or name of an integer variable.
+// args[4] - total, number of records in a cycle.
+func Stratabag(scope common.Scope, args ...interface{}) interface{} {
+	if len(args) < 5 {
+		panic(fmt.Errorf("stratabag expects file stratacol valcol idxvar total\n"))
+	}
+	filename := bagPath(scope, args[0].(string))
+	scol, vcol := toInt64(args[1]), toInt64(args[2])
+	idx, total := varInt64(scope, args[3]), toInt64(args[4])
+	if total <= 0 {
+		panic(fmt.Errorf("stratabag total %v must be positive\n", total))
+	}
+	key := fmt.Sprintf("stratabag:%v:%v:%v:%v", filename, scol, vcol, total)
+	table := bagDerived(key, func() interface{} {
+		return newStrataTable(bagRecords(scope, filename), scol, vcol, total)
+	}, bagEntryOf(scope, filename, false)).(*strataTable)
+	slot := idx % total
+	if slot < 0 {
+		slot += total
+	}
+	values := table.values[table.schedule[slot]]
+	return values[scope.GetRandom().Intn(len(values))]
+}
+
+func newStrataTable(records [][]string, scol, vcol, total int64) *strataTable {
+	table, strata := &strataTable{}, make(map[string]int)
+	for _, record := range records {
+		if scol >= int64(len(record)) || vcol >= int64(len(record)) {
+			panic(fmt.Errorf("stratabag column out of range in %v\n", record))
+		}
+		k, ok := strata[record[scol]]
+		if !ok {
+			k = len(table.values)
+			strata[record[scol]] = k
+			table.values = append(table.values, nil)
+		}
+		table.values[k] = append(table.values[k], record[vcol])
+	}
+	if len(table.values) == 0 {
+		panic(fmt.Errorf("stratabag expects atleast one record\n"))
+	}
+	// smooth weighted round-robin, weighted by size of each stratum.
+	current := make([]int, len(table.values))
+	table.schedule = make([]int, 0, total)
+	for i := int64(0); i < total; i++ {
+		max := 0
+		for k, values := range table.values {
+			current[k] += len(values)
+			if current[k] > current[max] {
+				max = k
+			}
+		}
+		current[max] -= len(records)
+		table.schedule = append(table.schedule, max)
+	}
+	return table
+}
@@ -0,0 +1,35 @@
+//  Copyright (c) 2013 Couchbase, Inc.
+
+package builtin
+
+import "fmt"
+import "strings"
+
+import "github.com/prataprc/monster/common"
+
+// Upper will return args[0] in upper case, non-string arguments are
+// converted to string.
+func Upper(scope common.Scope, args ...interface{}) interface{} {
+	if len(args) < 1 {
+		panic(fmt.Errorf("insufficient argument to upper\n"))
+	}
+	return strings.ToUpper(fmt.Sprintf("%v", args[0]))
+}
+
+// Lower will return args[0] in lower case, non-string arguments are
+// converted to string.
+func Lower(scope common.Scope, args ...interface{}) interface{} {
+	if len(args) < 1 {
+		panic(fmt.Errorf("insufficient argument to lower\n"))
+	}
+	return strings.ToLower(fmt.Sprintf("%v", args[0]))
+}
+
+// Trim will return args[0] with leading and trailing white space
+// removed, non-string arguments are converted to string.
+func Trim(scope common.Scope, args ...interface{}) interface{} {
+	if len(args) < 1 {
+		panic(fmt.Errorf("insufficient argument to trim\n"))
+	}
+	return strings.TrimSpace(fmt.Sprintf("%v", args[0]))
+}
@@ -0,0 +1,36 @@
+//  Copyright (c) 2013 Couchbase, Inc.
+
+package builtin
+
+import "fmt"
+
+import "github.com/prataprc/monster/common"
+
+// Substr will return a sub-string of args[0], indexed by characters
+// (runes) and not bytes.
+// args[0] - string
+// args[1] - start index, negative index counts from the end.
+// args[2] - optional, length of sub-string, if omitted or beyond the
+// end of string, sub-string is till the end of string.
+func Substr(scope common.Scope, args ...interface{}) interface{} {
+	if len(args) < 2 {
+		panic(fmt.Errorf("substr expects string and start\n"))
+	}
+	runes := []rune(fmt.Sprintf("%v", args[0]))
+	start, n := toInt64(args[1]), int64(len(runes))
+	if start < 0 {
+		start += n
+	}
+	if start < 0 {
+		start = 0
+	} else if start > n {
+		start = n
+	}
+	end := n
+	if len(args) > 2 {
+		if length := toInt64(args[2]); length >= 0 && start+length < n {
+			end = start + length
+		}
+	}
+	return string(runes[start:end])
+}
@@ -0,0 +1,45 @@
+//  Copyright (c) 2013 Couchbase, Inc.
+
+package builtin
+
+import "fmt"
+import "math"
+
+import "github.com/prataprc/monster/common"
+
+// Targetrate will return a random boolean, biased such that exactly
+// round(p*total) of the first `total` evaluations are true. Number of
+// evaluations and trues so far are tracked in global scope under
+// `name`. Beyond `total` evaluations it returns true with
+// probability `p`.
+// args[0] - p, target rate of true.
+// args[1] - total number of evaluations in the run.
+// args[2] - name to track the run under.
+func Targetrate(scope common.Scope, args ...interface{}) interface{} {
+	if len(args) < 3 {
+		panic(fmt.Errorf("targetrate expects p, total and name\n"))
+	}
+	p, total := toFloat64(args[0]), toInt64(args[1])
+	if p < 0 || p > 1 {
+		panic(fmt.Errorf("targetrate p %v not in [0,1]\n", p))
+	}
+	name := "_targetrate:" + args[2].(string)
+	state, _, ok := scope.Get(name)
+	if !ok {
+		state = [2]int64{0, 0} // {evaluations, trues}
+	}
+	counts := state.([2]int64)
+
+	prob := p
+	if remaining := total - counts[0]; remaining > 0 {
+		target := int64(math.Floor(p*float64(total) + 0.5))
+		prob = float64(target-counts[1]) / float64(remaining)
+	}
+	val := scope.GetRandom().Float64() < prob
+	counts[0]++
+	if val {
+		counts[1]++
+	}
+	scope.Set(name, counts, true /*global*/)
+	return val
+}
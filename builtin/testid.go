@@ -0,0 +1,18 @@
+//  Copyright (c) 2013 Couchbase, Inc.
+
+package builtin
+
+import "fmt"
+
+import "github.com/prataprc/monster/common"
+
+// Testid will generate prefix followed by `n` random digits and a
+// Luhn check digit, refer to Checkid.
+// args[0] - prefix, not included in the checksum.
+// args[1] - number of random digits.
+func Testid(scope common.Scope, args ...interface{}) interface{} {
+	if len(args) < 2 {
+		panic(fmt.Errorf("testid expects prefix and n\n"))
+	}
+	return Checkid(scope, args[0], args[1], "luhn")
+}
@@ -0,0 +1,35 @@
+//  Copyright (c) 2013 Couchbase, Inc.
+
+package builtin
+
+import "fmt"
+
+import "github.com/prataprc/monster/common"
+
+// DefaultFlipRate is the default probability with which `thresholdbool`
+// flips its outcome.
+const DefaultFlipRate = 0.05
+
+// Thresholdbool will return true if args[0] >= args[1], else false,
+// and flip the outcome with a small probability to model noise.
+// args[0] - score
+// args[1] - threshold
+// args[2] - optional, probability of flipping the outcome, defaults
+// to DefaultFlipRate.
+func Thresholdbool(scope common.Scope, args ...interface{}) interface{} {
+	if len(args) < 2 {
+		panic(fmt.Errorf("thresholdbool expects score and threshold\n"))
+	}
+	score, threshold, flip := toFloat64(args[0]), toFloat64(args[1]), DefaultFlipRate
+	if len(args) > 2 {
+		flip = toFloat64(args[2])
+	}
+	if flip < 0 || flip > 1 {
+		panic(fmt.Errorf("thresholdbool flip rate %v not in [0,1]\n", flip))
+	}
+	val := score >= threshold
+	if scope.GetRandom().Float64() < flip {
+		val = !val
+	}
+	return val
+}
@@ -0,0 +1,80 @@
+//  Copyright (c) 2013 Couchbase, Inc.
+
+package builtin
+
+import "fmt"
+import "math/rand"
+import "time"
+
+import "github.com/prataprc/monster/common"
+
+var _ = fmt.Sprintf("dummy")
+
+// toFloat64 will convert a numeric argument, parsed either as
+// INT or FLOAT, to float64.
+func toFloat64(arg interface{}) float64 {
+	switch val := arg.(type) {
+	case float64:
+		return val
+	case int64:
+		return float64(val)
+	}
+	panic(fmt.Errorf("expected number, got %T(%v)\n", arg, arg))
+}
+
+// toInt64 will convert an integer argument to int64.
+func toInt64(arg interface{}) int64 {
+	switch val := arg.(type) {
+	case int64:
+		return val
+	case Counter:
+		return int64(val)
+	}
+	panic(fmt.Errorf("expected integer, got %T(%v)\n", arg, arg))
+}
+
+// varInt64 will resolve an integer argument, that is either passed
+// as is or as the name of an integer variable in scope.
+func varInt64(scope common.Scope, arg interface{}) int64 {
+	if name, ok := arg.(string); ok {
+		val, _, ok := scope.Get(name)
+		if !ok {
+			panic(fmt.Errorf("unknown variable %v\n", name))
+		}
+		return toInt64(val)
+	}
+	return toInt64(arg)
+}
+
+// toDuration will convert a golang duration string, or seconds as
+// integer, to time.Duration.
+func toDuration(arg interface{}) time.Duration {
+	if s, ok := arg.(string); ok {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			panic(fmt.Errorf("parsing duration %v: %v\n", s, err))
+		}
+		return d
+	}
+	return time.Duration(toInt64(arg)) * time.Second
+}
+
+// pickWeighted will randomly pick an index into `weights`, with
+// the probability of each index proportional to its weight.
+func pickWeighted(rnd *rand.Rand, weights []float64) int {
+	total := 0.0
+	for _, w := range weights {
+		total += w
+	}
+	if total <= 0 {
+		panic(fmt.Errorf("total weight must be positive, got %v\n", total))
+	}
+	f := rnd.Float64() * total
+	for i, w := range weights {
+		if f < w {
+			return i
+		}
+		f -= w
+	}
+	return len(weights) - 1
+}
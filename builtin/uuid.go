@@ -8,6 +8,7 @@ import "strconv"
 import "io"
 import "encoding/binary"
 import "math/rand"
+import "time"
 import crypt "crypto/rand"
 
 import "github.com/prataprc/monster/common"
@@ -21,9 +22,41 @@ func init() {
 	rand.Seed(int64(seed))
 }
 
+// gregorianOffset is the number of 100ns intervals between the
+// UUID epoch, 1582-10-15, and the unix epoch.
+const gregorianOffset = 0x01B21DD213814000
+
 // Uuid returns a unique value based on current nanosecond timestamp.
+// When version is supplied a canonical, lowercase, hyphenated UUID
+// string is returned instead.
+// args[0] - optional, version 1 (time-based) or 4 (random).
 func Uuid(scope common.Scope, args ...interface{}) interface{} {
-	return newUUID().Uint64()
+	if len(args) == 0 {
+		return newUUID().Uint64()
+	}
+	rnd := scope.GetRandom()
+	u := make([]byte, 16)
+	switch version := toInt64(args[0]); version {
+	case 1:
+		ts := uint64(time.Now().UnixNano()/100) + gregorianOffset
+		binary.BigEndian.PutUint32(u[0:], uint32(ts))
+		binary.BigEndian.PutUint16(u[4:], uint16(ts>>32))
+		binary.BigEndian.PutUint16(u[6:], uint16(ts>>48)&0x0fff|0x1000)
+		binary.BigEndian.PutUint16(u[8:], uint16(rnd.Intn(0x4000)))
+		for i := 10; i < 16; i++ {
+			u[i] = byte(rnd.Intn(256))
+		}
+		u[10] |= 0x01 // random node-id, set multicast bit.
+	case 4:
+		for i := range u {
+			u[i] = byte(rnd.Intn(256))
+		}
+		u[6] = u[6]&0x0f | 0x40
+	default:
+		panic(fmt.Errorf("uuid unsupported version %v\n", version))
+	}
+	u[8] = u[8]&0x3f | 0x80 // RFC 4122 variant.
+	return fmt.Sprintf("%x-%x-%x-%x-%x", u[0:4], u[4:6], u[6:8], u[8:10], u[10:])
 }
 
 func newUUID() UUID {
@@ -0,0 +1,107 @@
+//  Copyright (c) 2013 Couchbase, Inc.
+
+package builtin
+
+import "fmt"
+import "math/rand"
+import "strconv"
+
+import "github.com/prataprc/monster/common"
+
+// aliasTable implements Walker's alias method, for O(1) weighted
+// sampling once the table is built.
+type aliasTable struct {
+	values []string
+	prob   []float64
+	alias  []int
+}
+
+// Wbagfast will fetch a random value from a bag, weighted by another
+// column of the same record. Weights are assumed to be stable for
+// the life of the bag and sampled using a cached alias table.
+// args[0] - filename.
+// args[1] - column index of value.
+// args[2] - column index of weight.
+func Wbagfast(scope common.Scope, args ...interface{}) interface{} {
+	if len(args) < 3 {
+		panic(fmt.Errorf("wbagfast expects filename, valcol and weightcol\n"))
+	}
+	filename := bagPath(scope, args[0].(string))
+	valcol, wcol := toInt64(args[1]), toInt64(args[2])
+	key := fmt.Sprintf("wbagfast:%v:%v:%v", filename, valcol, wcol)
+	table := bagDerived(key, func() interface{} {
+		values, weights := bagWeights(scope, filename, valcol, wcol)
+		return newAliasTable(values, weights)
+	}, bagEntryOf(scope, filename, false)).(*aliasTable)
+	return table.pick(scope.GetRandom())
+}
+
+// bagWeights will return the values and weights in columns `valcol`
+// and `wcol` of bag `filename`.
+func bagWeights(
+	scope common.Scope,
+	filename string,
+	valcol, wcol int64) ([]string, []float64) {
+
+	records := bagRecords(scope, filename)
+	values := make([]string, 0, len(records))
+	weights := make([]float64, 0, len(records))
+	for _, record := range records {
+		if valcol >= int64(len(record)) || wcol >= int64(len(record)) {
+			panic(fmt.Errorf("column out of range in %v: %v\n", filename, record))
+		}
+		w, err := strconv.ParseFloat(record[wcol], 64)
+		if err != nil || w < 0 {
+			fmsg := "invalid weight %q in %v\n"
+			panic(fmt.Errorf(fmsg, record[wcol], filename))
+		}
+		values, weights = append(values, record[valcol]), append(weights, w)
+	}
+	return values, weights
+}
+
+func newAliasTable(values []string, weights []float64) *aliasTable {
+	n, total := len(weights), 0.0
+	for _, w := range weights {
+		total += w
+	}
+	if n == 0 || total <= 0 {
+		panic(fmt.Errorf("total weight must be positive, got %v\n", total))
+	}
+	table := &aliasTable{
+		values: values, prob: make([]float64, n), alias: make([]int, n),
+	}
+	scaled := make([]float64, n)
+	small, large := make([]int, 0, n), make([]int, 0, n)
+	for i, w := range weights {
+		scaled[i] = w * float64(n) / total
+		if scaled[i] < 1.0 {
+			small = append(small, i)
+		} else {
+			large = append(large, i)
+		}
+	}
+	for len(small) > 0 && len(large) > 0 {
+		s, l := small[len(small)-1], large[len(large)-1]
+		small, large = small[:len(small)-1], large[:len(large)-1]
+		table.prob[s], table.alias[s] = scaled[s], l
+		scaled[l] -= 1.0 - scaled[s]
+		if scaled[l] < 1.0 {
+			small = append(small, l)
+		} else {
+			large = append(large, l)
+		}
+	}
+	for _, i := range append(small, large...) { // numerical leftovers.
+		table.prob[i] = 1.0
+	}
+	return table
+}
+
+func (table *aliasTable) pick(rnd *rand.Rand) string {
+	i := rnd.Intn(len(table.values))
+	if rnd.Float64() < table.prob[i] {
+		return table.values[i]
+	}
+	return table.values[table.alias[i]]
+}
@@ -0,0 +1,33 @@
+//  Copyright (c) 2013 Couchbase, Inc.
+
+package builtin
+
+import "testing"
+import "math/rand"
+import "strconv"
+
+func benchWeights(n int) ([]string, []float64) {
+	values, weights := make([]string, n), make([]float64, n)
+	for i := 0; i < n; i++ {
+		values[i], weights[i] = strconv.Itoa(i), float64(i%10+1)
+	}
+	return values, weights
+}
+
+func BenchmarkAliasPick(b *testing.B) {
+	table := newAliasTable(benchWeights(1000))
+	rnd := rand.New(rand.NewSource(1))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		table.pick(rnd)
+	}
+}
+
+func BenchmarkLinearPick(b *testing.B) {
+	values, weights := benchWeights(1000)
+	rnd := rand.New(rand.NewSource(1))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = values[pickWeighted(rnd, weights)]
+	}
+}
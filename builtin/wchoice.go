@@ -0,0 +1,36 @@
+//  Copyright (c) 2013 Couchbase, Inc.
+
+package builtin
+
+import "fmt"
+
+import "github.com/prataprc/monster/common"
+
+// Wchoice will pick one of the values with probability proportional
+// to its weight.
+// args[0], args[2] ... - value.
+// args[1], args[3] ... - weight of value, must not be negative.
+func Wchoice(scope common.Scope, args ...interface{}) interface{} {
+	if len(args) < 2 || len(args)%2 != 0 {
+		panic(fmt.Errorf("wchoice expects value/weight pairs\n"))
+	}
+	weights, total := make([]float64, 0, len(args)/2), 0.0
+	for i := 1; i < len(args); i += 2 {
+		w := toFloat64(args[i])
+		if w < 0 {
+			panic(fmt.Errorf("wchoice weight %v cannot be negative\n", w))
+		}
+		weights, total = append(weights, w), total+w
+	}
+	if total <= 0 {
+		panic(fmt.Errorf("wchoice total weight %v must be positive\n", total))
+	}
+	f := scope.GetRandom().Float64()
+	for i, w := range weights {
+		if f < w/total {
+			return args[i*2]
+		}
+		f -= w / total
+	}
+	return args[len(args)-2]
+}
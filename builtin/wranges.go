@@ -0,0 +1,39 @@
+//  Copyright (c) 2013 Couchbase, Inc.
+
+package builtin
+
+import "fmt"
+
+import "github.com/prataprc/monster/common"
+
+// Wranges will pick a band based on its weight and return a
+// uniformly random value within the band. If both bounds of the
+// picked band are integers an int64 is returned, else float64.
+// args[0], args[3] ... - weight of the band, must be positive.
+// args[1], args[4] ... - lower bound of the band, inclusive.
+// args[2], args[5] ... - upper bound of the band, exclusive.
+func Wranges(scope common.Scope, args ...interface{}) interface{} {
+	if len(args) < 3 || len(args)%3 != 0 {
+		panic(fmt.Errorf("wranges expects weight/lo/hi groups\n"))
+	}
+	weights := make([]float64, 0, len(args)/3)
+	for i := 0; i < len(args); i += 3 {
+		w := toFloat64(args[i])
+		if w <= 0 {
+			panic(fmt.Errorf("wranges weight %v must be positive\n", w))
+		} else if toFloat64(args[i+1]) >= toFloat64(args[i+2]) {
+			fmsg := "wranges lower bound %v not below %v\n"
+			panic(fmt.Errorf(fmsg, args[i+1], args[i+2]))
+		}
+		weights = append(weights, w)
+	}
+	rnd := scope.GetRandom()
+	i := pickWeighted(rnd, weights) * 3
+	lo, lok := args[i+1].(int64)
+	hi, hok := args[i+2].(int64)
+	if lok && hok {
+		return lo + rnd.Int63n(hi-lo)
+	}
+	flo, fhi := toFloat64(args[i+1]), toFloat64(args[i+2])
+	return flo + rnd.Float64()*(fhi-flo)
+}
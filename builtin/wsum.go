@@ -0,0 +1,22 @@
+//  Copyright (c) 2013 Couchbase, Inc.
+
+package builtin
+
+import "fmt"
+
+import "github.com/prataprc/monster/common"
+
+// Wsum will return the weighted sum of its arguments as float64.
+// args[0], args[2] ... args[N-1] - weight
+// args[1], args[3] ... args[N] - value
+// both weights and values can be int64 or float64.
+func Wsum(scope common.Scope, args ...interface{}) interface{} {
+	if len(args) < 2 || len(args)%2 != 0 {
+		panic(fmt.Errorf("wsum expects weight/value pairs\n"))
+	}
+	sum := 0.0
+	for i := 0; i < len(args); i += 2 {
+		sum += toFloat64(args[i]) * toFloat64(args[i+1])
+	}
+	return sum
+}
@@ -0,0 +1,30 @@
+//  Copyright (c) 2013 Couchbase, Inc.
+
+package builtin
+
+import "fmt"
+import "math/rand"
+
+import "github.com/prataprc/monster/common"
+
+// Zipf will return an int64 in [0,n) following Zipf distribution,
+// where lower values are more frequent. The generator is created
+// once for every (n,s) and cached in global scope.
+// args[0] - n, number of values.
+// args[1] - s, skew of the distribution, must be > 1.
+func Zipf(scope common.Scope, args ...interface{}) interface{} {
+	if len(args) < 2 {
+		panic(fmt.Errorf("zipf expects n and s\n"))
+	}
+	n, s := toInt64(args[0]), toFloat64(args[1])
+	if n < 1 || s <= 1 {
+		panic(fmt.Errorf("zipf expects n >= 1 and s > 1, got %v %v\n", n, s))
+	}
+	name := fmt.Sprintf("_zipf:%v:%v", n, s)
+	zipf, _, ok := scope.Get(name)
+	if !ok {
+		zipf = rand.NewZipf(scope.GetRandom(), s, 1, uint64(n-1))
+		scope.Set(name, zipf, true /*global*/)
+	}
+	return int64(zipf.(*rand.Zipf).Uint64())
+}
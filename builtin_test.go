@@ -0,0 +1,1908 @@
+//  Copyright (c) 2013 Couchbase, Inc.
+
+package monster
+
+import "testing"
+import "fmt"
+import "bytes"
+import "encoding/json"
+import "hash/fnv"
+import "io/ioutil"
+import "os"
+import "path/filepath"
+import "math"
+import "net"
+import "strings"
+import "sort"
+import "strconv"
+import "regexp"
+import "time"
+
+import "github.com/prataprc/goparsec"
+import "github.com/prataprc/monster/builtin"
+import "github.com/prataprc/monster/common"
+
+var _ = fmt.Sprintf("dummy")
+
+// compileText will compile production grammar `text` and build a
+// context for it, seeded with `seed`.
+func compileText(t *testing.T, text string, seed uint64) common.Scope {
+	root, _ := Y(parsec.NewScanner([]byte(text)))
+	scope, ok := root.(common.Scope)
+	if !ok {
+		t.Fatalf("unable to compile %q", text)
+	}
+	return BuildContext(scope, seed, "./testdata", "")
+}
+
+// evalText will generate `count` outputs from non-terminal `s` of
+// production grammar `text`.
+func evalText(t *testing.T, text string, seed uint64, count int) []string {
+	scope := compileText(t, text, seed)
+	nterms := scope["_nonterminals"].(common.NTForms)
+	outs := make([]string, 0, count)
+	for i := 0; i < count; i++ {
+		scope = scope.RebuildContext()
+		outs = append(outs, EvalForms("root", scope, nterms["s"]).(string))
+	}
+	return outs
+}
+
+// checkSeeded will verify that production grammar `text` generates
+// the same outputs for the same seed.
+func checkSeeded(t *testing.T, text string, seed uint64) {
+	outs1, outs2 := evalText(t, text, seed, 10), evalText(t, text, seed, 10)
+	for i := range outs1 {
+		if outs1[i] != outs2[i] {
+			t.Fatalf("expected %q, got %q for same seed", outs1[i], outs2[i])
+		}
+	}
+}
+
+func TestHistogram(t *testing.T) {
+	scope := compileText(t, ``, 100)
+	counts, n := make([]int, 3), 30000
+	for i := 0; i < n; i++ {
+		v := builtin.Histogram(scope, 0.0, 10.0, 1.0, 20.0, 2.0, 40.0, 1.0)
+		f := v.(float64)
+		switch {
+		case f >= 0 && f < 10:
+			counts[0]++
+		case f >= 10 && f < 20:
+			counts[1]++
+		case f >= 20 && f < 40:
+			counts[2]++
+		default:
+			t.Fatalf("sample %v out of histogram edges", f)
+		}
+	}
+	for i, ratio := range []float64{0.25, 0.5, 0.25} {
+		if r := float64(counts[i]) / float64(n); r < ratio-0.02 || r > ratio+0.02 {
+			t.Fatalf("bucket %v expected rate %v, got %v", i, ratio, r)
+		}
+	}
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatalf("expected panic for unordered edges")
+			}
+		}()
+		builtin.Histogram(scope, 0.0, 10.0, 1.0, 5.0, 1.0)
+	}()
+	outs := evalText(t, `s : (histogram 0 10 1 20 1).`, 100, 10)
+	for _, out := range outs {
+		var f float64
+		if _, err := fmt.Sscan(out, &f); err != nil || f < 0 || f >= 20 {
+			t.Fatalf("unexpected histogram output %q", out)
+		}
+	}
+}
+
+func TestJbagKeyVal(t *testing.T) {
+	colors := map[string]string{
+		"red": "#ff0000", "green": "#00ff00", "blue": "#0000ff",
+		"black": `{"rgb":[0,0,0]}`,
+	}
+	text := `s : (jbagkey "colors.json") " " (jbagval "colors.json").`
+	outs := evalText(t, text, 200, 50)
+	keys, vals := make(map[string]bool), make(map[string]bool)
+	for _, out := range outs {
+		var key, val string
+		fmt.Sscan(out, &key, &val)
+		if _, ok := colors[key]; !ok {
+			t.Fatalf("unexpected key in %q", out)
+		}
+		keys[key] = true
+		for _, v := range colors {
+			if v == val {
+				vals[val] = true
+			}
+		}
+	}
+	if len(keys) != len(colors) || len(vals) != len(colors) {
+		t.Fatalf("expected all keys and values, got %v %v", keys, vals)
+	}
+	checkSeeded(t, text, 200)
+}
+
+func TestPipe(t *testing.T) {
+	testcases := [][2]string{
+		{
+			`s : (pipe (bag "names") (sprintf "<%v>" $_) (sprintf "%v!" $_)).`,
+			`s : (sprintf "%v!" (sprintf "<%v>" (bag "names"))).`,
+		},
+		{
+			`s : (pipe (bag "padnames") (trim $_) (upper $_)).`,
+			`s : (upper (trim (bag "padnames"))).`,
+		},
+	}
+	for _, tcase := range testcases {
+		outs1, outs2 := evalText(t, tcase[0], 300, 20), evalText(t, tcase[1], 300, 20)
+		for i := range outs1 {
+			if outs1[i] != outs2[i] {
+				t.Fatalf("expected %q, got %q", outs2[i], outs1[i])
+			}
+		}
+	}
+	// nested pipe restores outer `$_`.
+	text := `s : (pipe "a" (sprintf "%v-%v" (pipe "b" (upper $_)) $_)).`
+	if outs := evalText(t, text, 300, 1); outs[0] != "B-a" {
+		t.Fatalf("expected B-a, got %q", outs[0])
+	}
+	// `$_` is not visible after pipe.
+	text = `s : (pipe "a" (upper $_)) $_.`
+	if _, err := GenerateOne(text, 300, "./testdata", "", "s"); err == nil {
+		t.Fatalf("expected $_ to be unbound after pipe")
+	}
+	// `$_x` is not lexed as `$_` followed by x.
+	if _, err := Parse(`s : (pipe "a" (upper $_)) $_x.`); err == nil {
+		t.Fatalf("expected parse error for $_x")
+	}
+}
+
+func TestLognormal(t *testing.T) {
+	scope := compileText(t, ``, 400)
+	below, n := 0, 20000
+	for i := 0; i < n; i++ {
+		f := builtin.Lognormal(scope, 1.0, 0.5).(float64)
+		if f <= 0 {
+			t.Fatalf("lognormal sample %v not positive", f)
+		} else if f < math.E { // median is exp(mu)
+			below++
+		}
+	}
+	if r := float64(below) / float64(n); r < 0.48 || r > 0.52 {
+		t.Fatalf("expected half the samples below median, got %v", r)
+	}
+	for i := 0; i < 1000; i++ {
+		if f := builtin.Lognormal(scope, 1.0, 2.0, 10.0).(float64); f > 10 {
+			t.Fatalf("expected sample clamped to 10, got %v", f)
+		}
+	}
+	checkSeeded(t, `s : (lognormal 0.0 1.0).`, 400)
+}
+
+func TestWbagfast(t *testing.T) {
+	scope := compileText(t, ``, 500)
+	counts, n := make(map[string]int), 20000
+	for i := 0; i < n; i++ {
+		counts[builtin.Wbagfast(scope, "weighted.csv", int64(0), int64(1)).(string)]++
+	}
+	expected := map[string]float64{"small": 0.1, "medium": 0.3, "large": 0.6}
+	for val, ratio := range expected {
+		if r := float64(counts[val]) / float64(n); math.Abs(r-ratio) > 0.02 {
+			t.Fatalf("expected %v at rate %v, got %v", val, ratio, r)
+		}
+	}
+	if counts["none"] > 0 {
+		t.Fatalf("zero weight value picked %v times", counts["none"])
+	}
+}
+
+func TestNorepeat(t *testing.T) {
+	outs := evalText(t, `s : (norepeat "a" "b" "c").`, 600, 200)
+	for i := 1; i < len(outs); i++ {
+		if outs[i] == outs[i-1] {
+			t.Fatalf("consecutive repeat %q at %v", outs[i], i)
+		}
+	}
+	outs = evalText(t, `s : (norepeat "a") (norepeat "a").`, 600, 5)
+	for _, out := range outs {
+		if out != "aa" {
+			t.Fatalf("expected single option to repeat, got %q", out)
+		}
+	}
+	// forms with different arguments keep separate history.
+	outs = evalText(t, `s : (norepeat "x" "xx" "xxx") "," (norepeat "xx" "x" "xxx").`, 600, 100)
+	same := 0
+	for _, out := range outs {
+		if parts := strings.Split(out, ","); parts[0] == parts[1] {
+			same++
+		}
+	}
+	if same == 0 {
+		t.Fatalf("expected independent picks, got %v", outs)
+	}
+	// uncomparable arguments, like records from bagslice.
+	scope := compileText(t, ``, 600)
+	a, b := []string{"a"}, []string{"b"}
+	last := ""
+	for i := 0; i < 10; i++ {
+		val := fmt.Sprint(builtin.Norepeat(scope, a, b))
+		if val == last {
+			t.Fatalf("consecutive repeat %v", val)
+		}
+		last = val
+	}
+}
+
+func TestBagsample(t *testing.T) {
+	outs := evalText(t, `s : (bagsample "people.csv" 3).`, 700, 20)
+	for _, out := range outs {
+		var rows []map[string]string
+		if err := json.Unmarshal([]byte(out), &rows); err != nil {
+			t.Fatalf("invalid JSON %q: %v", out, err)
+		} else if len(rows) != 3 {
+			t.Fatalf("expected 3 rows, got %v", out)
+		}
+		names := make(map[string]bool)
+		for _, row := range rows {
+			if row["name"] == "" || row["age"] == "" || row["city"] == "" {
+				t.Fatalf("expected header keys in %v", row)
+			}
+			names[row["name"]] = true
+		}
+		if len(names) != 3 {
+			t.Fatalf("expected distinct rows, got %v", out)
+		}
+	}
+}
+
+func TestLocalenum(t *testing.T) {
+	enum := `(localenum locale "y" "en:Yes" "fr:Oui" "n" "en:No" "fr:Non")`
+	labels := map[string]map[string]string{
+		"en": {"y": "Yes", "n": "No"},
+		"fr": {"y": "Oui", "n": "Non"},
+		"de": {"y": "Yes", "n": "No"}, // fallback to first label.
+	}
+	for locale, expected := range labels {
+		text := fmt.Sprintf("s : (let locale %q) %v \"=\" $localenum.", locale, enum)
+		for _, out := range evalText(t, text, 800, 20) {
+			parts := strings.Split(out, "=")
+			if expected[parts[1]] != parts[0] {
+				t.Fatalf("unexpected label %q for locale %v", out, locale)
+			}
+		}
+	}
+}
+
+func TestBusinesstime(t *testing.T) {
+	texts := map[string][2]int{
+		`s : (businesstime "2006-01-02T15:04:05Z07:00").`: {9, 17},
+		`s : (businesstime "2006-01-02T15:04:05Z07:00" "2015-03-01T00:00:00Z" "2015-04-01T00:00:00Z" 10 12).`: {10, 12},
+	}
+	for text, hours := range texts {
+		for _, out := range evalText(t, text, 900, 500) {
+			tm, err := time.Parse(time.RFC3339, out)
+			if err != nil {
+				t.Fatal(err)
+			} else if wd := tm.Weekday(); wd == time.Saturday || wd == time.Sunday {
+				t.Fatalf("expected weekday, got %v", out)
+			} else if tm.Hour() < hours[0] || tm.Hour() >= hours[1] {
+				t.Fatalf("expected business hours %v, got %v", hours, out)
+			}
+		}
+		checkSeeded(t, text, 900)
+	}
+	// range starting and ending within business hours.
+	from, _ := time.Parse(time.RFC3339, "2015-03-02T15:00:00Z")
+	till, _ := time.Parse(time.RFC3339, "2015-03-04T12:00:00Z")
+	text := `s : (businesstime "2006-01-02T15:04:05Z07:00" "2015-03-02T15:00:00Z" "2015-03-04T12:00:00Z").`
+	for _, out := range evalText(t, text, 900, 500) {
+		tm, err := time.Parse(time.RFC3339, out)
+		if err != nil {
+			t.Fatal(err)
+		} else if tm.Before(from) || !tm.Before(till) {
+			t.Fatalf("expected time within %v-%v, got %v", from, till, out)
+		}
+	}
+}
+
+func TestFullname(t *testing.T) {
+	text := `s : (fullname "fr") "|" $first "|" $last "|" $full.`
+	for _, out := range evalText(t, text, 1000, 20) {
+		parts := strings.Split(out, "|")
+		if parts[0] != parts[3] || parts[3] != parts[1]+" "+parts[2] {
+			t.Fatalf("inconsistent full name %q", out)
+		}
+	}
+	checkSeeded(t, text, 1000)
+}
+
+func TestSmoothbag(t *testing.T) {
+	scope := compileText(t, ``, 1100)
+	sample := func(alpha float64) map[string]float64 {
+		counts, n := make(map[string]float64), 20000
+		for i := 0; i < n; i++ {
+			val := builtin.Smoothbag(scope, "weighted.csv", int64(0), int64(1), alpha)
+			counts[val.(string)]++
+		}
+		for val := range counts {
+			counts[val] /= float64(n)
+		}
+		return counts
+	}
+	rates := sample(0.0)
+	raw := map[string]float64{"small": 0.1, "medium": 0.3, "large": 0.6, "none": 0}
+	for val, ratio := range raw {
+		if math.Abs(rates[val]-ratio) > 0.02 {
+			t.Fatalf("expected %v at raw rate %v, got %v", val, ratio, rates[val])
+		}
+	}
+	rates = sample(1000.0)
+	for val := range raw {
+		if math.Abs(rates[val]-0.25) > 0.02 {
+			t.Fatalf("expected %v near uniform, got %v", val, rates[val])
+		}
+	}
+}
+
+func TestDebugchoice(t *testing.T) {
+	var buf bytes.Buffer
+	scope := compileText(t, `s : (debugchoice "a" "b" "c").`, 1200)
+	scope.SetDebugWriter(&buf)
+	nterms := scope["_nonterminals"].(common.NTForms)
+	outs := make([]string, 0)
+	for i := 0; i < 20; i++ {
+		scope = scope.RebuildContext()
+		outs = append(outs, EvalForms("root", scope, nterms["s"]).(string))
+	}
+	choices := evalText(t, `s : (choice "a" "b" "c").`, 1200, 20)
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != len(outs) {
+		t.Fatalf("expected %v debug lines, got %v", len(outs), len(lines))
+	}
+	for i, out := range outs {
+		if out != choices[i] {
+			t.Fatalf("expected %q as choice, got %q", choices[i], out)
+		} else if !strings.Contains(lines[i], "picked "+out) {
+			t.Fatalf("expected %q logged, got %q", out, lines[i])
+		}
+	}
+}
+
+func TestBenford(t *testing.T) {
+	scope := compileText(t, ``, 1300)
+	counts, n := make([]int, 10), 30000
+	for i := 0; i < n; i++ {
+		s := strconv.FormatInt(builtin.Benford(scope, int64(6)).(int64), 10)
+		if len(s) != 6 {
+			t.Fatalf("expected 6 digits, got %v", s)
+		}
+		counts[s[0]-'0']++
+	}
+	for d := 1; d <= 9; d++ {
+		expected := math.Log10(1 + 1/float64(d))
+		if r := float64(counts[d]) / float64(n); math.Abs(r-expected) > 0.01 {
+			t.Fatalf("leading digit %v expected rate %v, got %v", d, expected, r)
+		}
+	}
+}
+
+func TestPrototext(t *testing.T) {
+	text := `s : (prototext "name" "a \"b\"" "id" 10 "ok" true "addr" (prototext "zip" 560001)).`
+	out := evalText(t, text, 1400, 1)[0]
+	ref := "name: \"a \\\"b\\\"\"\nid: 10\nok: true\naddr {\n  zip: 560001\n}"
+	if out != ref {
+		t.Fatalf("expected %q, got %q", ref, out)
+	}
+}
+
+func TestBagwhere(t *testing.T) {
+	active := map[string]bool{"u1": true, "u3": true, "u4": true}
+	seen := make(map[string]bool)
+	for _, out := range evalText(t, `s : (bagwhere "users.csv" 0 1 "active").`, 1500, 50) {
+		if !active[out] {
+			t.Fatalf("expected active user, got %q", out)
+		}
+		seen[out] = true
+	}
+	if len(seen) != len(active) {
+		t.Fatalf("expected all active users, got %v", seen)
+	}
+	scope := compileText(t, ``, 1500)
+	defer func() {
+		if r := recover(); r == nil {
+			t.Fatalf("expected panic for no matching records")
+		}
+	}()
+	builtin.Bagwhere(scope, "users.csv", int64(0), int64(1), "deleted")
+}
+
+func TestPartition(t *testing.T) {
+	for _, out := range evalText(t, `s : (partition 100 4).`, 1600, 50) {
+		var parts []int64
+		if err := json.Unmarshal([]byte(out), &parts); err != nil {
+			t.Fatal(err)
+		} else if len(parts) != 4 {
+			t.Fatalf("expected 4 parts, got %v", out)
+		}
+		sum := int64(0)
+		for _, part := range parts {
+			if part < 0 {
+				t.Fatalf("expected non-negative parts, got %v", out)
+			}
+			sum += part
+		}
+		if sum != 100 {
+			t.Fatalf("expected parts to sum to 100, got %v", out)
+		}
+	}
+}
+
+func TestPassword(t *testing.T) {
+	text := `s : (password 12).`
+	classes := []string{"ABCDEFGHIJKLMNOPQRSTUVWXYZ", "abcdefghijklmnopqrstuvwxyz",
+		"0123456789", "!@#$%^&*()-_=+[]{};:,.?"}
+	for _, out := range evalText(t, text, 1700, 100) {
+		if len(out) != 12 {
+			t.Fatalf("expected length 12, got %q", out)
+		}
+		for _, class := range classes {
+			if !strings.ContainsAny(out, class) {
+				t.Fatalf("expected one of %q in %q", class, out)
+			}
+		}
+	}
+	checkSeeded(t, text, 1700)
+}
+
+func TestPareto(t *testing.T) {
+	scope := compileText(t, ``, 1900)
+	tail, n := 0, 20000
+	for i := 0; i < n; i++ {
+		f := builtin.Pareto(scope, 2.0, 1.5).(float64)
+		if f < 2.0 {
+			t.Fatalf("expected sample above xm, got %v", f)
+		} else if f > 8.0 {
+			tail++
+		}
+	}
+	expected := math.Pow(2.0/8.0, 1.5) // P(X > x) = (xm/x)^alpha
+	if r := float64(tail) / float64(n); math.Abs(r-expected) > 0.01 {
+		t.Fatalf("expected tail rate %v, got %v", expected, r)
+	}
+}
+
+func TestBagmatch(t *testing.T) {
+	scope := compileText(t, ``, 2000)
+	refs := map[string]string{
+		"sku-101": "gadget", // exact
+		"sku-205": "gizmo",  // prefix
+		"tool-19": "hammer", // prefix
+	}
+	for key, ref := range refs {
+		out := builtin.Bagmatch(scope, "products.csv", int64(0), int64(1), key)
+		if out != ref {
+			t.Fatalf("expected %v for %v, got %v", ref, key, out)
+		}
+	}
+	outs := evalText(t, `s : (let key "sku-2") (bagmatch "products.csv" 0 1 $key).`, 2000, 1)
+	if outs[0] != "gizmo" {
+		t.Fatalf("expected gizmo, got %v", outs[0])
+	}
+}
+
+func TestWranges(t *testing.T) {
+	scope := compileText(t, ``, 2100)
+	bands, halves, n := make([]int, 2), make([]int, 2), 20000
+	for i := 0; i < n; i++ {
+		v := builtin.Wranges(scope, int64(1), int64(0), int64(10), int64(3), int64(100), int64(200))
+		switch x := v.(int64); {
+		case x >= 0 && x < 10:
+			bands[0]++
+		case x >= 100 && x < 200:
+			bands[1]++
+			if x < 150 {
+				halves[0]++
+			} else {
+				halves[1]++
+			}
+		default:
+			t.Fatalf("sample %v out of bands", x)
+		}
+	}
+	if r := float64(bands[1]) / float64(n); math.Abs(r-0.75) > 0.02 {
+		t.Fatalf("expected second band at rate 0.75, got %v", r)
+	}
+	if r := float64(halves[0]) / float64(bands[1]); math.Abs(r-0.5) > 0.02 {
+		t.Fatalf("expected uniform within band, got %v", r)
+	}
+	checkSeeded(t, `s : (wranges 1 0.0 1.0 2 5 10).`, 2100)
+}
+
+func TestPerm(t *testing.T) {
+	checkPerm := func(perm []int) {
+		seen := make(map[int]bool)
+		for _, x := range perm {
+			if x < 0 || x >= 10 || seen[x] {
+				t.Fatalf("invalid permutation %v", perm)
+			}
+			seen[x] = true
+		}
+	}
+	for _, out := range evalText(t, `s : (perm 10).`, 2200, 10) {
+		var perm []int
+		if err := json.Unmarshal([]byte(out), &perm); err != nil {
+			t.Fatal(err)
+		}
+		checkPerm(perm)
+	}
+	checkSeeded(t, `s : (perm 10).`, 2200)
+
+	// i-th element of a fixed permutation, across documents.
+	text := `s : (perm 10 0) (perm 10 1) (perm 10 2) (perm 10 3) (perm 10 4)
+                 (perm 10 5) (perm 10 6) (perm 10 7) (perm 10 8) (perm 10 9).`
+	outs := evalText(t, text, 2200, 3)
+	perm := make([]int, 0, 10)
+	for _, ch := range outs[0] {
+		perm = append(perm, int(ch-'0'))
+	}
+	checkPerm(perm)
+	if outs[1] != outs[0] || outs[2] != outs[0] {
+		t.Fatalf("expected fixed permutation across documents, got %v", outs)
+	}
+}
+
+func TestTargetrate(t *testing.T) {
+	for _, total := range []int{10, 37, 500} {
+		text := fmt.Sprintf(`s : (targetrate 0.3 %v "flag").`, total)
+		trues := 0
+		for _, out := range evalText(t, text, 2300, total) {
+			if out == "true" {
+				trues++
+			}
+		}
+		if expected := int(math.Floor(0.3*float64(total) + 0.5)); trues != expected {
+			t.Fatalf("expected %v trues in %v, got %v", expected, total, trues)
+		}
+	}
+}
+
+func TestRememberRecall(t *testing.T) {
+	text := `s : id "=" (recall "id").
+             id : (remember "id" (range 1000000)).`
+	for _, out := range evalText(t, text, 2400, 10) {
+		if parts := strings.Split(out, "="); parts[0] != parts[1] {
+			t.Fatalf("expected recalled value, got %q", out)
+		}
+	}
+
+	scope := compileText(t, ``, 2400)
+	builtin.Remember(scope, "x", int64(10))
+	if val := builtin.Recall(scope, "x"); val != int64(10) {
+		t.Fatalf("expected 10, got %v", val)
+	}
+	scope = scope.RebuildContext()
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("expected remembered value reset after RebuildContext")
+		}
+	}()
+	builtin.Recall(scope, "x")
+}
+
+func TestDiurnaltime(t *testing.T) {
+	scope := compileText(t, ``, 2500)
+	hours, n := make([]float64, 24), 40000
+	for i := 0; i < n; i++ {
+		out := builtin.Diurnaltime(scope, time.RFC3339).(string)
+		tm, err := time.Parse(time.RFC3339, out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		hours[tm.Hour()]++
+	}
+	total := 0.0
+	for _, w := range builtin.DiurnalCurve {
+		total += w
+	}
+	for hour, w := range builtin.DiurnalCurve {
+		if r := hours[hour] / float64(n); math.Abs(r-w/total) > 0.01 {
+			t.Fatalf("hour %v expected rate %v, got %v", hour, w/total, r)
+		}
+	}
+}
+
+func TestWsum(t *testing.T) {
+	text := `s : (let a 10) (let b 2.5) (let c 1000) (wsum 0.5 $a 2 $b 0 $c).`
+	if out := evalText(t, text, 2600, 1)[0]; out != "10" {
+		t.Fatalf("expected 10, got %v", out)
+	}
+	scope := compileText(t, ``, 2600)
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("expected panic for non-numeric value")
+		}
+	}()
+	builtin.Wsum(scope, 1.0, "x")
+}
+
+func TestBagquota(t *testing.T) {
+	counts := make(map[string]int)
+	for _, out := range evalText(t, `s : (bagquota "names" 2).`, 2700, 10) {
+		if counts[out]++; counts[out] > 2 {
+			t.Fatalf("%v exceeded quota", out)
+		}
+	}
+	if len(counts) != 5 {
+		t.Fatalf("expected all 5 names used twice, got %v", counts)
+	}
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("expected panic on exhausted quota")
+		}
+	}()
+	evalText(t, `s : (bagquota "names" 2).`, 2700, 11)
+}
+
+func TestNested(t *testing.T) {
+	out := evalText(t, `s : (nested 5 (range 100)).`, 2800, 1)[0]
+	var obj map[string]interface{}
+	if err := json.Unmarshal([]byte(out), &obj); err != nil {
+		t.Fatalf("invalid JSON %q: %v", out, err)
+	}
+	depth, leaves := 0, make(map[float64]bool)
+	for obj != nil {
+		depth++
+		leaves[obj["leaf"].(float64)] = true
+		obj, _ = obj["child"].(map[string]interface{})
+	}
+	if depth != 5 {
+		t.Fatalf("expected depth 5, got %v in %v", depth, out)
+	} else if len(leaves) < 2 {
+		t.Fatalf("expected leaf form evaluated per level, got %v", out)
+	}
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("expected panic for huge depth")
+		}
+	}()
+	evalText(t, `s : (nested 100000 "x").`, 2800, 1)
+}
+
+func TestMixture(t *testing.T) {
+	scope := compileText(t, ``, 2900)
+	low, high, valley, n := 0, 0, 0, 20000
+	for i := 0; i < n; i++ {
+		f := builtin.Mixture(scope, 1.0, -5.0, 1.0, 3.0, 5.0, 1.0).(float64)
+		switch {
+		case f > -7 && f < -3:
+			low++
+		case f > 3 && f < 7:
+			high++
+		case f > -1 && f < 1:
+			valley++
+		}
+	}
+	if r := float64(low) / float64(n); math.Abs(r-0.25*0.954) > 0.02 {
+		t.Fatalf("expected mode around -5 at rate 0.24, got %v", r)
+	} else if r := float64(high) / float64(n); math.Abs(r-0.75*0.954) > 0.02 {
+		t.Fatalf("expected mode around 5 at rate 0.72, got %v", r)
+	} else if valley > n/1000 {
+		t.Fatalf("expected few samples between modes, got %v", valley)
+	}
+}
+
+func TestBagf(t *testing.T) {
+	text := `s : (bagf "users.csv" "{0}:{2}/{1}").`
+	rows := map[string]bool{
+		"u1:admin/active": true, "u2:user/inactive": true, "u3:user/active": true,
+		"u4:user/active": true, "u5:admin/inactive": true, "u6:user/locked": true,
+	}
+	for _, out := range evalText(t, text, 3000, 20) {
+		if !rows[out] {
+			t.Fatalf("unexpected substitution %q", out)
+		}
+	}
+	checkSeeded(t, text, 3000)
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("expected panic for out of range placeholder")
+		}
+	}()
+	evalText(t, `s : (bagf "users.csv" "{3}").`, 3000, 1)
+}
+
+func TestSessionlen(t *testing.T) {
+	scope := compileText(t, ``, 3100)
+	below, sum, n := 0, 0.0, 20000
+	for i := 0; i < n; i++ {
+		secs := builtin.Sessionlen(scope).(int64)
+		if secs < 1 || secs > builtin.DefaultSessionMax {
+			t.Fatalf("session length %v out of bounds", secs)
+		} else if secs < builtin.DefaultSessionMedian {
+			below++
+		}
+		sum += float64(secs)
+	}
+	if r := float64(below) / float64(n); math.Abs(r-0.5) > 0.02 {
+		t.Fatalf("expected half the sessions below median, got %v", r)
+	} else if mean := sum / float64(n); mean < 1.3*builtin.DefaultSessionMedian {
+		t.Fatalf("expected long tail to skew the mean, got %v", mean)
+	}
+	checkSeeded(t, `s : (sessionlen 60 600).`, 3100)
+}
+
+func TestUpperLower(t *testing.T) {
+	text := `s : name "," (lower "MiXeD") "," (upper true).
+             name : (upper (bag "names")).`
+	names := map[string]bool{"ALICE": true, "BOB": true, "CAROL": true, "DAVE": true, "EVE": true}
+	for _, out := range evalText(t, text, 3200, 10) {
+		parts := strings.Split(out, ",")
+		if !names[parts[0]] || parts[1] != "mixed" || parts[2] != "TRUE" {
+			t.Fatalf("unexpected case conversion %q", out)
+		}
+	}
+}
+
+func TestInterval(t *testing.T) {
+	layout := "2006-01-02T15:04:05Z07:00"
+	text := fmt.Sprintf(`s : (interval %q "30m" "2h") "|" (intervalend %q).`, layout, layout)
+	for _, out := range evalText(t, text, 3300, 100) {
+		parts := strings.Split(out, "|")
+		start, err1 := time.Parse(time.RFC3339, parts[0])
+		end, err2 := time.Parse(time.RFC3339, parts[1])
+		if err1 != nil || err2 != nil {
+			t.Fatalf("unable to parse %q", out)
+		}
+		if gap := end.Sub(start); gap < 30*time.Minute || gap > 2*time.Hour {
+			t.Fatalf("gap %v not within bounds in %q", gap, out)
+		}
+	}
+	scope := compileText(t, ``, 3300)
+	defer func() {
+		r := recover()
+		msg := "end 2015-01-01T00:00:00Z is not after start 2015-02-01T00:00:00Z\n"
+		if err, ok := r.(error); !ok || err.Error() != msg {
+			t.Fatalf("expected %q, got %v", msg, r)
+		}
+	}()
+	from, till := "2015-02-01T00:00:00Z", "2015-01-01T00:00:00Z"
+	builtin.Interval(scope, layout, "30m", "2h", from, till)
+}
+
+func TestReplace(t *testing.T) {
+	refs := map[string]string{
+		`s : (replace "a-b-c" "-" "+").`:                     "a+b+c",
+		`s : (replace "a-b-c" "-" "+" 1).`:                   "a+b-c",
+		`s : (replace "abc" "" "+").`:                        "abc",
+		`s : (replace (sprintf "id-%v-%v" 1 2) "-" "_" -1).`: "id_1_2",
+	}
+	for text, ref := range refs {
+		if out := evalText(t, text, 3400, 1)[0]; out != ref {
+			t.Fatalf("expected %q, got %q", ref, out)
+		}
+	}
+}
+
+func TestLocalebag(t *testing.T) {
+	greets := map[string]map[string]bool{
+		"en": {"hello": true, "hi": true},
+		"fr": {"bonjour": true, "salut": true},
+	}
+	for locale, refs := range greets {
+		text := fmt.Sprintf(`s : (let locale %q) (localebag "greet_{locale}").`, locale)
+		for _, out := range evalText(t, text, 3500, 10) {
+			if !refs[out] {
+				t.Fatalf("unexpected %q for locale %v", out, locale)
+			}
+		}
+	}
+}
+
+func TestSubstr(t *testing.T) {
+	refs := map[string]string{
+		`s : (substr "abcdef" 1 3).`:  "bcd",
+		`s : (substr "abcdef" 2).`:    "cdef",
+		`s : (substr "héllo" 1 2).`:   "él",
+		`s : (substr "abcdef" -2).`:   "ef",
+		`s : (substr "abcdef" -3 1).`: "d",
+		`s : (substr "abcdef" 4 10).`: "ef",
+		`s : (substr "abc" 10).`:      "",
+	}
+	for text, ref := range refs {
+		if out := evalText(t, text, 3600, 1)[0]; out != ref {
+			t.Fatalf("%v expected %q, got %q", text, ref, out)
+		}
+	}
+}
+
+func TestMultibag(t *testing.T) {
+	fr := map[string]bool{"bonjour": true, "salut": true}
+	outs, frcount := evalText(t, `s : (multibag 1 "greet_en" 3 "greet_fr").`, 3700, 10000), 0
+	for _, out := range outs {
+		if fr[out] {
+			frcount++
+		}
+	}
+	if r := float64(frcount) / float64(len(outs)); math.Abs(r-0.75) > 0.02 {
+		t.Fatalf("expected greet_fr at rate 0.75, got %v", r)
+	}
+	checkSeeded(t, `s : (multibag 1 "greet_en" 3 "greet_fr").`, 3700)
+}
+
+func TestRepeat(t *testing.T) {
+	for _, out := range evalText(t, `s : (repeat (choice "ab" "cd") 3).`, 3800, 20) {
+		if out != "ababab" && out != "cdcdcd" {
+			t.Fatalf("expected inner form evaluated once, got %q", out)
+		}
+	}
+	if out := evalText(t, `s : "[" (repeat "x" -2) "]".`, 3800, 1)[0]; out != "[]" {
+		t.Fatalf("expected negative count as zero, got %q", out)
+	}
+}
+
+func TestProgression(t *testing.T) {
+	scope := compileText(t, ``, 3900)
+	for i := int64(0); i < 100; i++ {
+		scope.Set("idx", i, false)
+		base := 100 + float64(i)*2.5
+		if f := builtin.Progression(scope, int64(i), 100.0, 2.5, 0.0).(float64); f != base {
+			t.Fatalf("expected %v without jitter, got %v", base, f)
+		}
+		f := builtin.Progression(scope, "idx", 100.0, 2.5, 0.5).(float64)
+		if math.Abs(f-base) > 0.5 {
+			t.Fatalf("expected %v within jitter 0.5, got %v", base, f)
+		}
+	}
+}
+
+func TestHostname(t *testing.T) {
+	re := regexp.MustCompile(`^[a-z]+-[a-z]+-[0-9]{2}\.[a-z]+-[a-z]+$`)
+	for _, out := range evalText(t, `s : (hostname).`, 4000, 50) {
+		if !re.MatchString(out) {
+			t.Fatalf("invalid hostname %q", out)
+		}
+	}
+	checkSeeded(t, `s : (hostname).`, 4000)
+}
+
+func TestArith(t *testing.T) {
+	scope := compileText(t, ``, 4100)
+	refs := []struct {
+		fn   func(common.Scope, ...interface{}) interface{}
+		args []interface{}
+		ref  interface{}
+	}{
+		{builtin.Add, []interface{}{int64(1), int64(2), int64(3)}, int64(6)},
+		{builtin.Add, []interface{}{int64(1), 2.5}, 3.5},
+		{builtin.Sub, []interface{}{int64(10), int64(3), int64(2)}, int64(5)},
+		{builtin.Sub, []interface{}{10.0, int64(3)}, 7.0},
+		{builtin.Mul, []interface{}{int64(2), int64(3), int64(4)}, int64(24)},
+		{builtin.Mul, []interface{}{int64(2), 0.5}, 1.0},
+		{builtin.Div, []interface{}{int64(7), int64(2)}, int64(3)},
+		{builtin.Div, []interface{}{int64(7), 2.0}, 3.5},
+	}
+	for _, ref := range refs {
+		if out := ref.fn(scope, ref.args...); out != ref.ref {
+			t.Fatalf("%v expected %T(%v), got %T(%v)", ref.args, ref.ref, ref.ref, out, out)
+		}
+	}
+	if out := evalText(t, `s : (add (mul 2 3) (div 9 3)).`, 4100, 1)[0]; out != "9" {
+		t.Fatalf("expected 9, got %v", out)
+	}
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("expected panic on div by zero")
+		}
+	}()
+	builtin.Div(scope, int64(1), int64(0))
+}
+
+func TestMod(t *testing.T) {
+	scope := compileText(t, `s : (mod (inc counter) 5) (inc other).`, 4200)
+	scope.Set("counter", int64(-1), false /*global*/)
+	scope.Set("other", int64(0), false /*global*/)
+	nterms := scope["_nonterminals"].(common.NTForms)
+	for i := 0; i < 12; i++ {
+		scope = scope.RebuildContext()
+		out := EvalForms("root", scope, nterms["s"]).(string)
+		if ref := strconv.Itoa(i % 5); out != ref {
+			t.Fatalf("expected %v, got %v", ref, out)
+		}
+	}
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("expected panic on mod by zero")
+		}
+	}()
+	builtin.Mod(scope, int64(1), int64(0))
+}
+
+func TestBagslice(t *testing.T) {
+	roles := map[string]string{
+		"u1": "admin", "u2": "user", "u3": "user", "u4": "user", "u5": "admin", "u6": "user",
+	}
+	text := `s : (let row (bagslice "users.csv")) (index $row 0) "," (index $row 2)
+                 "," (join "|" $row).`
+	for _, out := range evalText(t, text, 4300, 20) {
+		parts := strings.Split(out, ",")
+		if roles[parts[0]] != parts[1] || !strings.HasPrefix(parts[2], parts[0]+"|") {
+			t.Fatalf("inconsistent columns %q", out)
+		}
+	}
+	scope := compileText(t, ``, 4300)
+	for i := 0; i < 20; i++ {
+		row := builtin.Bagslice(scope, "users.csv").([]string)
+		row[0] = "mutated"
+	}
+	for i := int64(0); i < 6; i++ {
+		if val := builtin.Bagn(scope, "users.csv", int64(0), i); val == "mutated" {
+			t.Fatalf("cached bag mutated through bagslice")
+		}
+	}
+}
+
+func TestMinMax(t *testing.T) {
+	scope := compileText(t, ``, 4400)
+	if out := builtin.Min(scope, int64(3), int64(1), int64(2)); out != int64(1) {
+		t.Fatalf("expected int64(1), got %T(%v)", out, out)
+	} else if out := builtin.Max(scope, int64(3), 4.5, int64(2)); out != 4.5 {
+		t.Fatalf("expected 4.5, got %T(%v)", out, out)
+	} else if out := builtin.Min(scope, int64(3), 4.5); out != 3.0 {
+		t.Fatalf("expected float64(3), got %T(%v)", out, out)
+	} else if out := builtin.Max(scope, "x"); out != "x" {
+		t.Fatalf("expected single argument unchanged, got %v", out)
+	}
+	for _, out := range evalText(t, `s : (min (range 1 1000) 256).`, 4400, 50) {
+		if n, _ := strconv.Atoi(out); n < 1 || n > 256 {
+			t.Fatalf("expected value clamped to 256, got %v", out)
+		}
+	}
+}
+
+func TestGeometric(t *testing.T) {
+	scope := compileText(t, ``, 4500)
+	sum, n := 0.0, 20000
+	for i := 0; i < n; i++ {
+		trials := builtin.Geometric(scope, 0.2).(int64)
+		if trials < 1 {
+			t.Fatalf("expected atleast one trial, got %v", trials)
+		}
+		sum += float64(trials)
+	}
+	if mean := sum / float64(n); math.Abs(mean-5) > 0.2 {
+		t.Fatalf("expected mean near 1/p = 5, got %v", mean)
+	}
+}
+
+func TestSeq(t *testing.T) {
+	outs := evalText(t, `s : (seq "id") "," (seq "odd" 1 2).`, 4600, 5)
+	refs := []string{"0,1", "1,3", "2,5", "3,7", "4,9"}
+	for i, out := range outs {
+		if out != refs[i] {
+			t.Fatalf("expected %v, got %v", refs[i], out)
+		}
+	}
+}
+
+func TestNatstr(t *testing.T) {
+	counts, n := make([]float64, 26), 0
+	for _, out := range evalText(t, `s : (natstr 50).`, 4700, 1000) {
+		if len(out) != 50 {
+			t.Fatalf("expected length 50, got %q", out)
+		}
+		for _, ch := range out {
+			counts[ch-'a']++
+			n++
+		}
+	}
+	for i, freq := range builtin.LetterFrequencies {
+		if r := counts[i] * 100 / float64(n); math.Abs(r-freq) > 0.5 {
+			t.Fatalf("letter %c expected %v%%, got %v%%", 'a'+i, freq, r)
+		}
+	}
+	checkSeeded(t, `s : (natstr 10).`, 4700)
+}
+
+func TestRandfloat(t *testing.T) {
+	scope := compileText(t, ``, 4800)
+	for decimals := int64(0); decimals < 4; decimals++ {
+		for i := 0; i < 1000; i++ {
+			f := builtin.Randfloat(scope, 1.0, 3.14159, decimals).(float64)
+			s := strconv.FormatFloat(f, 'f', -1, 64)
+			if f < 1.0 || f > 3.14159 {
+				t.Fatalf("%v out of range", f)
+			} else if i := strings.Index(s, "."); i >= 0 && int64(len(s)-i-1) > decimals {
+				t.Fatalf("%v has more than %v decimals", s, decimals)
+			}
+		}
+	}
+	for _, out := range evalText(t, `s : (randfloat 0 1).`, 4800, 50) {
+		if i := strings.Index(out, "."); i >= 0 && len(out)-i-1 > 2 {
+			t.Fatalf("%v has more than 2 decimals", out)
+		}
+	}
+}
+
+func TestIban(t *testing.T) {
+	// well known valid IBAN.
+	if builtin.IbanMod97("370400440532013000DE89") != 1 {
+		t.Fatalf("expected DE89370400440532013000 to validate")
+	}
+	lengths := map[string]int{
+		"BE": 16, "DE": 22, "ES": 24, "FR": 27, "GB": 22, "IT": 27, "NL": 18,
+	}
+	for country, length := range lengths {
+		text := fmt.Sprintf(`s : (iban %q).`, country)
+		for _, out := range evalText(t, text, 4900, 20) {
+			if len(out) != length || out[:2] != country {
+				t.Fatalf("invalid %v iban %v", country, out)
+			} else if builtin.IbanMod97(out[4:]+out[:4]) != 1 {
+				t.Fatalf("iban %v fails mod-97 check", out)
+			}
+		}
+	}
+}
+
+func TestThresholdbool(t *testing.T) {
+	scope := compileText(t, ``, 5000)
+	pass, fail, n := 0, 0, 20000
+	for i := 0; i < n; i++ {
+		if builtin.Thresholdbool(scope, int64(80), int64(50)).(bool) {
+			pass++
+		}
+		if builtin.Thresholdbool(scope, 20.0, int64(50), 0.2).(bool) {
+			fail++
+		}
+	}
+	if r := float64(pass) / float64(n); math.Abs(r-(1-builtin.DefaultFlipRate)) > 0.01 {
+		t.Fatalf("expected pass rate %v, got %v", 1-builtin.DefaultFlipRate, r)
+	} else if r := float64(fail) / float64(n); math.Abs(r-0.2) > 0.01 {
+		t.Fatalf("expected noisy pass rate 0.2 below threshold, got %v", r)
+	}
+	checkSeeded(t, `s : (thresholdbool (range 100) 50).`, 5000)
+}
+
+func TestZipf(t *testing.T) {
+	counts := make([]int, 100)
+	for _, out := range evalText(t, `s : (zipf 100 1.5).`, 5100, 5000) {
+		n, err := strconv.Atoi(out)
+		if err != nil || n < 0 || n >= 100 {
+			t.Fatalf("unexpected zipf output %q", out)
+		}
+		counts[n]++
+	}
+	low, high := counts[0]+counts[1]+counts[2], 0
+	for _, count := range counts[50:] {
+		high += count
+	}
+	if low < 2500 || low < 5*high {
+		t.Fatalf("expected low indices to dominate, got %v vs %v", low, high)
+	}
+	checkSeeded(t, `s : (zipf 100 1.5).`, 5100)
+}
+
+func TestBursttime(t *testing.T) {
+	text := `s : (bursttime "2006-01-02T15:04:05.999999999Z07:00" 0.2 "5s").`
+	outs, jumps := evalText(t, text, 5200, 5000), 0
+	prev, _ := time.Parse(time.RFC3339Nano, outs[0])
+	for _, out := range outs[1:] {
+		tm, err := time.Parse(time.RFC3339Nano, out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if gap := tm.Sub(prev); gap < 0 || gap >= 5*time.Second {
+			jumps++
+		}
+		prev = tm
+	}
+	if r := float64(jumps) / float64(len(outs)-1); math.Abs(r-0.2) > 0.02 {
+		t.Fatalf("expected jumps at rate 0.2, got %v", r)
+	}
+}
+
+func TestRanddate(t *testing.T) {
+	text := `s : (randdate "2015-03-01T00:00:00Z" "2015-03-10T00:00:00Z") "|"
+                (randdate "2015-03-01T00:00:00Z" "2015-03-01T06:00:00Z"
+                          "2006-01-02T15:04:05Z07:00").`
+	start, _ := time.Parse(time.RFC3339, "2015-03-01T00:00:00Z")
+	for _, out := range evalText(t, text, 5300, 200) {
+		parts := strings.Split(out, "|")
+		day, err := time.Parse("2006-01-02", parts[0])
+		if err != nil {
+			t.Fatal(err)
+		} else if day.Before(start) || day.After(start.AddDate(0, 0, 9)) {
+			t.Fatalf("date %v out of range", parts[0])
+		}
+		tm, err := time.Parse(time.RFC3339, parts[1])
+		if err != nil {
+			t.Fatal(err)
+		} else if tm.Before(start) || tm.After(start.Add(6*time.Hour)) {
+			t.Fatalf("time %v out of range", parts[1])
+		}
+	}
+	checkSeeded(t, text, 5300)
+
+	scope := compileText(t, ``, 5300)
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("expected panic for start after end")
+		}
+	}()
+	builtin.Randdate(scope, "2015-03-10T00:00:00Z", "2015-03-01T00:00:00Z")
+}
+
+func TestBagshard(t *testing.T) {
+	shardof := func(s string, nshards uint32) uint32 {
+		h := fnv.New32a()
+		h.Write([]byte(s))
+		return h.Sum32() % nshards
+	}
+	for shard := 0; shard < 2; shard++ {
+		text := fmt.Sprintf(`s : (let key (range 0 20)) $key ":"
+                                 (bagshard "users.csv" $key 2 %v).`, shard)
+		keys := make(map[string]string)
+		for i := 0; i < 3; i++ {
+			for _, out := range evalText(t, text, uint64(5400+i), 100) {
+				parts := strings.Split(out, ":")
+				if shardof(parts[1], 2) != uint32(shard) {
+					t.Fatalf("%v does not belong to shard %v", parts[1], shard)
+				} else if prev, ok := keys[parts[0]]; ok && prev != parts[1] {
+					t.Fatalf("key %v mapped to %v and %v", parts[0], prev, parts[1])
+				}
+				keys[parts[0]] = parts[1]
+			}
+		}
+	}
+	// without shard, key lands in its own shard, under any seed and in
+	// separate contexts.
+	lines := make(map[string]string)
+	for seed := uint64(5400); seed < 5410; seed++ {
+		scope := compileText(t, ``, seed)
+		for i := 0; i < 20; i++ {
+			key := fmt.Sprintf("key%v", i)
+			if shard := builtin.ShardOf(key, 3); shard != int64(shardof(key, 3)) {
+				t.Fatalf("expected %v in shard %v, got %v", key, shardof(key, 3), shard)
+			}
+			line := builtin.Bagshard(scope, "users.csv", key, int64(3)).(string)
+			if builtin.ShardOf(line, 3) != builtin.ShardOf(key, 3) {
+				t.Fatalf("%v for %v not in shard %v", line, key, builtin.ShardOf(key, 3))
+			} else if prev, ok := lines[key]; ok && prev != line {
+				t.Fatalf("key %v mapped to %v and %v", key, prev, line)
+			}
+			lines[key] = line
+		}
+	}
+}
+
+func TestEpoch(t *testing.T) {
+	start, _ := time.Parse(time.RFC3339, "2015-03-01T00:00:00Z")
+	end, _ := time.Parse(time.RFC3339, "2015-04-01T00:00:00Z")
+	testcases := []struct {
+		unit       string
+		start, end int64
+	}{
+		{`"s"`, start.Unix(), end.Unix()},
+		{`"ms"`, start.UnixNano() / 1e6, end.UnixNano() / 1e6},
+		{`"ns"`, start.UnixNano(), end.UnixNano()},
+		{``, start.Unix(), end.Unix()},
+	}
+	for _, tcase := range testcases {
+		text := fmt.Sprintf(`s : (epoch "2015-03-01T00:00:00Z" "2015-04-01T00:00:00Z" %v).`,
+			tcase.unit)
+		for _, out := range evalText(t, text, 5500, 200) {
+			n, err := strconv.ParseInt(out, 10, 64)
+			if err != nil {
+				t.Fatal(err)
+			} else if n < tcase.start || n > tcase.end {
+				t.Fatalf("epoch %v out of range for unit %v", n, tcase.unit)
+			}
+		}
+		checkSeeded(t, text, 5500)
+	}
+	scope := compileText(t, ``, 5500)
+	val := builtin.Epoch(scope, "2015-03-01T00:00:00Z", "2015-04-01T00:00:00Z", "ms")
+	if _, ok := val.(int64); !ok {
+		t.Fatalf("expected int64, got %T", val)
+	}
+}
+
+func TestBivariate(t *testing.T) {
+	for _, corr := range []float64{0.8, -0.5, 0} {
+		text := fmt.Sprintf(`s : (bivariate 170 70 10 15 %v).`, corr)
+		outs := evalText(t, text, 5600, 5000)
+		xs, ys := make([]float64, len(outs)), make([]float64, len(outs))
+		mx, my := 0.0, 0.0
+		for i, out := range outs {
+			parts := strings.Split(out, ",")
+			xs[i], _ = strconv.ParseFloat(parts[0], 64)
+			ys[i], _ = strconv.ParseFloat(parts[1], 64)
+			mx, my = mx+xs[i], my+ys[i]
+		}
+		mx, my = mx/float64(len(outs)), my/float64(len(outs))
+		sxy, sxx, syy := 0.0, 0.0, 0.0
+		for i := range xs {
+			sxy += (xs[i] - mx) * (ys[i] - my)
+			sxx += (xs[i] - mx) * (xs[i] - mx)
+			syy += (ys[i] - my) * (ys[i] - my)
+		}
+		if r := sxy / math.Sqrt(sxx*syy); math.Abs(r-corr) > 0.05 {
+			t.Fatalf("expected correlation %v, got %v", corr, r)
+		}
+	}
+	scope := compileText(t, ``, 5600)
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("expected panic for corr out of range")
+		}
+	}()
+	builtin.Bivariate(scope, 0.0, 0.0, 1.0, 1.0, 1.5)
+}
+
+func TestInvcdf(t *testing.T) {
+	text := `s : (invcdf 0 10 0.5 100 0.9 150 1 1000).`
+	outs := evalText(t, text, 5700, 4001)
+	vals := make([]float64, len(outs))
+	for i, out := range outs {
+		vals[i], _ = strconv.ParseFloat(out, 64)
+		if vals[i] < 10 || vals[i] > 1000 {
+			t.Fatalf("value %v out of range", vals[i])
+		}
+	}
+	sort.Float64s(vals)
+	if median := vals[len(vals)/2]; math.Abs(median-100) > 5 {
+		t.Fatalf("expected median near 100, got %v", median)
+	}
+	checkSeeded(t, text, 5700)
+
+	scope := compileText(t, ``, 5700)
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("expected panic for decreasing quantiles")
+		}
+	}()
+	builtin.Invcdf(scope, 0.5, 1.0, 0.2, 2.0)
+}
+
+func TestEmail(t *testing.T) {
+	re := regexp.MustCompile(`^[a-z]+@[a-z]+\.[a-z]+$`)
+	for _, out := range evalText(t, `s : (email).`, 5800, 200) {
+		if !re.MatchString(out) {
+			t.Fatalf("unexpected email %q", out)
+		}
+	}
+	text := `s : (email "emailnames" "domains").`
+	seen := make(map[string]bool)
+	for _, out := range evalText(t, text, 5800, 200) {
+		seen[out] = true
+	}
+	for _, local := range []string{"maryann", "johnsmith", "bob"} {
+		for _, domain := range []string{"example.com", "mail.org"} {
+			if !seen[local+"@"+domain] {
+				t.Fatalf("expected %v@%v in %v", local, domain, seen)
+			}
+		}
+	}
+	if len(seen) != 6 {
+		t.Fatalf("unexpected emails %v", seen)
+	}
+	checkSeeded(t, text, 5800)
+}
+
+func TestRandip(t *testing.T) {
+	text := `s : (randipv4) "|" (randipv4 "10.20.0.0/14") "|" (randipv6).`
+	_, network, _ := net.ParseCIDR("10.20.0.0/14")
+	for _, out := range evalText(t, text, 5900, 200) {
+		parts := strings.Split(out, "|")
+		if ip := net.ParseIP(parts[0]); ip == nil || ip.To4() == nil {
+			t.Fatalf("invalid ipv4 %q", parts[0])
+		} else if ip := net.ParseIP(parts[1]); ip == nil || !network.Contains(ip) {
+			t.Fatalf("%q not in %v", parts[1], network)
+		} else if ip := net.ParseIP(parts[2]); ip == nil || ip.To16() == nil {
+			t.Fatalf("invalid ipv6 %q", parts[2])
+		}
+	}
+	checkSeeded(t, text, 5900)
+}
+
+func TestCounted(t *testing.T) {
+	text := `s : (counted "colors" "red" "green" "blue").`
+	scope := compileText(t, text, 6000)
+	nterms := scope["_nonterminals"].(common.NTForms)
+	emitted := make(map[string]int64)
+	for i := 0; i < 1000; i++ {
+		scope = scope.RebuildContext()
+		emitted[EvalForms("root", scope, nterms["s"]).(string)]++
+	}
+	counts := common.SelectionCounts(scope, "colors")
+	if len(counts) != 3 {
+		t.Fatalf("expected 3 options, got %v", counts)
+	}
+	for option, n := range emitted {
+		if counts[option] != n {
+			t.Fatalf("expected %v for %v, got %v", n, option, counts[option])
+		}
+	}
+	if counts := common.SelectionCounts(scope, "sizes"); counts != nil {
+		t.Fatalf("expected no counts, got %v", counts)
+	}
+}
+
+func TestUuid(t *testing.T) {
+	re := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-([0-9a-f])[0-9a-f]{3}-([89ab])[0-9a-f]{3}-[0-9a-f]{12}$`)
+	for _, version := range []string{"1", "4"} {
+		text := fmt.Sprintf(`s : (uuid %v).`, version)
+		seen := make(map[string]bool)
+		for _, out := range evalText(t, text, 6100, 100) {
+			if m := re.FindStringSubmatch(out); m == nil {
+				t.Fatalf("invalid uuid %q", out)
+			} else if m[1] != version {
+				t.Fatalf("expected version %v, got %q", version, out)
+			} else if seen[out] {
+				t.Fatalf("duplicate uuid %q", out)
+			}
+			seen[out] = true
+		}
+	}
+	checkSeeded(t, `s : (uuid 4).`, 6100)
+	if _, err := strconv.ParseUint(evalText(t, `s : (uuid).`, 6100, 1)[0], 10, 64); err != nil {
+		t.Fatalf("expected uint64 without version, %v", err)
+	}
+}
+
+func TestDynwchoice(t *testing.T) {
+	text := `s : (let w (choice 0 1)) $w ":"
+                (dynwchoice $w "hot" (sub 1 $w) "cold").`
+	for _, out := range evalText(t, text, 6200, 200) {
+		if out != "0:cold" && out != "1:hot" {
+			t.Fatalf("weights did not follow variable, got %q", out)
+		}
+	}
+	text = `s : (let w (choice 1 9)) $w ":" (dynwchoice $w "a" 1 "b").`
+	counts := map[string]int{}
+	for _, out := range evalText(t, text, 6200, 4000) {
+		counts[out]++
+	}
+	if r := float64(counts["9:a"]) / float64(counts["9:a"]+counts["9:b"]); math.Abs(r-0.9) > 0.03 {
+		t.Fatalf("expected 0.9 for w=9, got %v", r)
+	}
+	if r := float64(counts["1:a"]) / float64(counts["1:a"]+counts["1:b"]); math.Abs(r-0.5) > 0.04 {
+		t.Fatalf("expected 0.5 for w=1, got %v", r)
+	}
+	checkSeeded(t, text, 6200)
+}
+
+func TestShuffle(t *testing.T) {
+	texts := map[string]string{
+		",": `s : (shuffle "a" "b" "c" 10 2.5).`,
+		"|": `s : (shuffle "sep=|" "a" "b" "c" 10 2.5).`,
+	}
+	for sep, text := range texts {
+		orders := make(map[string]bool)
+		for _, out := range evalText(t, text, 6300, 100) {
+			items := strings.Split(out, sep)
+			sort.Strings(items)
+			if strings.Join(items, ",") != "10,2.5,a,b,c" {
+				t.Fatalf("expected each item once, got %q", out)
+			}
+			orders[out] = true
+		}
+		if len(orders) < 20 {
+			t.Fatalf("expected shuffled orders, got %v", orders)
+		}
+		checkSeeded(t, text, 6300)
+	}
+}
+
+func TestBagjoin(t *testing.T) {
+	text := `s : (bagjoin "people.csv" "name" "accounts.csv" "id").`
+	plans := map[string]string{"alice": "gold", "bob": "free", "carol": "pro"}
+	seen := make(map[string]bool)
+	for _, out := range evalText(t, text, 6400, 100) {
+		var row map[string]string
+		if err := json.Unmarshal([]byte(out), &row); err != nil {
+			t.Fatal(err)
+		} else if row["name"] != row["id"] {
+			t.Fatalf("inconsistent join keys in %v", out)
+		} else if plan, ok := plans[row["name"]]; !ok || plan != row["plan"] {
+			t.Fatalf("unexpected joined row %v", out)
+		} else if row["city"] == "" {
+			t.Fatalf("missing left columns in %v", out)
+		}
+		seen[row["name"]] = true
+	}
+	if len(seen) != len(plans) {
+		t.Fatalf("expected all matched keys, got %v", seen)
+	}
+	checkSeeded(t, text, 6400)
+}
+
+func TestDriftchoice(t *testing.T) {
+	scope := compileText(t, ``, 6500)
+	total := int64(10000)
+	early, late := 0, 0
+	for i := int64(0); i < total; i++ {
+		scope.Set("idx", i, false)
+		val := builtin.Driftchoice(scope, "idx", total, "a", 9.0, 1.0, "b", 1.0, 9.0)
+		if val == "a" && i < 1000 {
+			early++
+		} else if val == "a" && i >= total-1000 {
+			late++
+		}
+	}
+	if r := float64(early) / 1000; r < 0.8 {
+		t.Fatalf("expected early records to favor a, got %v", r)
+	} else if r := float64(late) / 1000; r > 0.2 {
+		t.Fatalf("expected late records to favor b, got %v", r)
+	}
+	text := `s : (let i 0) (driftchoice $i 10 "x" 1 0 "y" 0 1).`
+	for _, out := range evalText(t, text, 6500, 20) {
+		if out != "x" {
+			t.Fatalf("expected initial distribution at idx 0, got %v", out)
+		}
+	}
+}
+
+func TestWchoice(t *testing.T) {
+	text := `s : (wchoice "a" 70 "b" 20 "c" 10.0 "d" 0).`
+	counts, n := make(map[string]int), 10000
+	for _, out := range evalText(t, text, 6600, n) {
+		counts[out]++
+	}
+	for val, ratio := range map[string]float64{"a": 0.7, "b": 0.2, "c": 0.1, "d": 0} {
+		if r := float64(counts[val]) / float64(n); math.Abs(r-ratio) > 0.02 {
+			t.Fatalf("expected %v at rate %v, got %v", val, ratio, r)
+		}
+	}
+	checkSeeded(t, text, 6600)
+
+	scope := compileText(t, ``, 6600)
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("expected panic for zero total weight")
+		}
+	}()
+	builtin.Wchoice(scope, "a", int64(0), "b", int64(0))
+}
+
+func TestBagn(t *testing.T) {
+	text := `s : (bagn "products.csv" 1) "|" (bagn "products.csv" 5).`
+	names := map[string]bool{"widget|": true, "gadget|": true, "gizmo|": true, "hammer|": true}
+	for _, out := range evalText(t, text, 6700, 50) {
+		if !names[out] {
+			t.Fatalf("unexpected %q", out)
+		}
+	}
+	text = `s : (let r (range 0 100)) (bagn "products.csv" 0 $r) "," (bagn "products.csv" 1 $r).`
+	pairs := map[string]bool{
+		"sku-100,widget": true, "sku-101,gadget": true,
+		"sku-2,gizmo": true, "tool-1,hammer": true,
+	}
+	for _, out := range evalText(t, text, 6700, 50) {
+		if !pairs[out] {
+			t.Fatalf("columns not from the same record %q", out)
+		}
+	}
+	checkSeeded(t, text, 6700)
+}
+
+func TestStratabag(t *testing.T) {
+	scope := compileText(t, ``, 6800)
+	strata := map[string]string{
+		"u1": "active", "u2": "inactive", "u3": "active",
+		"u4": "active", "u5": "inactive", "u6": "locked",
+	}
+	counts := make(map[string]int)
+	for i := int64(0); i < 600; i++ {
+		scope.Set("idx", i, false)
+		val := builtin.Stratabag(scope, "users.csv", int64(1), int64(0), "idx", int64(6))
+		counts[strata[val.(string)]]++
+	}
+	expected := map[string]int{"active": 300, "inactive": 200, "locked": 100}
+	for stratum, n := range expected {
+		if counts[stratum] != n {
+			t.Fatalf("expected %v for %v, got %v", n, stratum, counts)
+		}
+	}
+	checkSeeded(t, `s : (let i (range 0 100)) (stratabag "users.csv" 1 0 $i 12).`, 6800)
+}
+
+func TestBagrow(t *testing.T) {
+	text := `s : (bagrow "people.csv" 0) "," (bagrow "people.csv" 1) ","
+                (bagrow "people.csv" 2).`
+	rows := map[string]bool{
+		"name,age,city": true, "alice,31,Paris": true, "bob,42,London": true,
+		"carol,27,Berlin": true, "dave,35,Madrid": true, "eve,29,Rome": true,
+	}
+	seen := make(map[string]bool)
+	for _, out := range evalText(t, text, 6900, 100) {
+		if !rows[out] {
+			t.Fatalf("fields not from the same row %q", out)
+		}
+		seen[out] = true
+	}
+	if len(seen) < 2 {
+		t.Fatalf("expected rows to differ across documents, got %v", seen)
+	}
+	checkSeeded(t, text, 6900)
+}
+
+func TestCondbag(t *testing.T) {
+	text := `s : (let state (choice "CA" "NY" "TX")) $state ":"
+                (condbag "cities.csv" 0 1 $state).`
+	cities := map[string]bool{
+		"CA:San Francisco": true, "CA:Los Angeles": true,
+		"NY:New York": true, "NY:Buffalo": true, "TX:Austin": true,
+	}
+	seen := make(map[string]bool)
+	for _, out := range evalText(t, text, 7000, 200) {
+		if !cities[out] {
+			t.Fatalf("inconsistent city for state %q", out)
+		}
+		seen[out] = true
+	}
+	if len(seen) != len(cities) {
+		t.Fatalf("expected all cities, got %v", seen)
+	}
+	checkSeeded(t, text, 7000)
+
+	scope := compileText(t, ``, 7000)
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("expected panic for unknown key")
+		}
+	}()
+	builtin.Condbag(scope, "cities.csv", int64(0), int64(1), "WA")
+}
+
+func TestJbag(t *testing.T) {
+	texts := map[string]map[string]bool{
+		`s : (jbag "fruits.json").`:        {"apple": true, "banana": true, "cherry": true},
+		`s : (jbag "people.json" "name").`: {"alice": true, "bob": true, "carol": true},
+		`s : (jbag "people.json" "age").`:  {"31": true, "42": true, "27": true},
+	}
+	for text, values := range texts {
+		seen := make(map[string]bool)
+		for _, out := range evalText(t, text, 7100, 100) {
+			if !values[out] {
+				t.Fatalf("unexpected %q for %v", out, text)
+			}
+			seen[out] = true
+		}
+		if len(seen) != len(values) {
+			t.Fatalf("expected all of %v, got %v", values, seen)
+		}
+		checkSeeded(t, text, 7100)
+	}
+	scope := compileText(t, ``, 7100)
+	defer func() {
+		if r := recover(); r == nil || !strings.Contains(fmt.Sprint(r), "JSON array") {
+			t.Fatalf("expected panic for malformed JSON, got %v", r)
+		}
+	}()
+	builtin.Jbag(scope, "malformed.json")
+}
+
+func TestBudget(t *testing.T) {
+	text := `s : (budget "spend" 1000 (range 1 100)).`
+	sum, zeros := int64(0), 0
+	for _, out := range evalText(t, text, 7200, 100) {
+		n, err := strconv.ParseInt(out, 10, 64)
+		if err != nil {
+			t.Fatal(err)
+		} else if n == 0 {
+			zeros++
+		}
+		sum += n
+	}
+	if sum != 1000 {
+		t.Fatalf("expected budget to be exhausted at 1000, got %v", sum)
+	} else if zeros == 0 {
+		t.Fatalf("expected 0 once budget is exhausted")
+	}
+	text = `s : (budget "spend" 50.5 (rangef 0.0 10.0)).`
+	total := 0.0
+	for _, out := range evalText(t, text, 7200, 100) {
+		f, _ := strconv.ParseFloat(out, 64)
+		total += f
+	}
+	if total > 50.5+1e-9 {
+		t.Fatalf("expected sum within 50.5, got %v", total)
+	}
+}
+
+func TestBagu(t *testing.T) {
+	for _, recycle := range []bool{true, false} {
+		text := fmt.Sprintf(`s : (bagu "names" %v).`, recycle)
+		scope := compileText(t, text, 7300)
+		nterms := scope["_nonterminals"].(common.NTForms)
+		seen := make(map[string]bool)
+		for i := 0; i < 5; i++ {
+			scope = scope.RebuildContext()
+			out := EvalForms("root", scope, nterms["s"]).(string)
+			if seen[out] {
+				t.Fatalf("repeated %q before exhausting bag", out)
+			}
+			seen[out] = true
+		}
+		func() {
+			defer func() {
+				if r := recover(); recycle && r != nil {
+					t.Fatalf("expected recycle, got %v", r)
+				} else if !recycle && r == nil {
+					t.Fatalf("expected panic once exhausted")
+				}
+			}()
+			scope = scope.RebuildContext()
+			if out := EvalForms("root", scope, nterms["s"]).(string); !seen[out] {
+				t.Fatalf("unexpected %q after recycle", out)
+			}
+		}()
+	}
+}
+
+func TestCheckid(t *testing.T) {
+	// well known check digits.
+	known := [][2]string{
+		{"luhn", "79927398713"}, {"mod10", "4006381333931"},
+		{"mod11", "86011117947"},
+	}
+	for _, tcase := range known {
+		if !builtin.ValidCheckID(tcase[0], tcase[1]) {
+			t.Fatalf("expected %v to validate under %v", tcase[1], tcase[0])
+		}
+	}
+	for _, algorithm := range []string{"luhn", "mod10", "mod11"} {
+		text := fmt.Sprintf(`s : (checkid "ACC-" 9 %q).`, algorithm)
+		for _, out := range evalText(t, text, 7400, 100) {
+			if !strings.HasPrefix(out, "ACC-") || len(out) != 14 {
+				t.Fatalf("unexpected id %q", out)
+			} else if !builtin.ValidCheckID(algorithm, out[4:]) {
+				t.Fatalf("%v fails %v check", out, algorithm)
+			}
+		}
+		checkSeeded(t, text, 7400)
+	}
+}
+
+func TestBagexcept(t *testing.T) {
+	text := `s : (bagexcept "users.csv" 1 "inactive" "locked").`
+	for _, out := range evalText(t, text, 7500, 100) {
+		if out != "active" {
+			t.Fatalf("expected only active, got %q", out)
+		}
+	}
+	text = `s : (bagexcept "names" 0 "bob").`
+	seen := make(map[string]bool)
+	for _, out := range evalText(t, text, 7500, 100) {
+		seen[out] = true
+	}
+	if len(seen) != 4 || seen["bob"] {
+		t.Fatalf("expected all names except bob, got %v", seen)
+	}
+	checkSeeded(t, text, 7500)
+
+	scope := compileText(t, ``, 7500)
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("expected panic when all values are excluded")
+		}
+	}()
+	builtin.Bagexcept(scope, "users.csv", int64(1), "active", "inactive", "locked")
+}
+
+func TestAnnealchoice(t *testing.T) {
+	scope := compileText(t, ``, 7600)
+	total := int64(20000)
+	early, late := make(map[string]int), make(map[string]int)
+	for i := int64(0); i < total; i++ {
+		scope.Set("idx", i, false)
+		val := builtin.Annealchoice(scope, "idx", total, "a", "b", "c").(string)
+		if i < 2000 {
+			early[val]++
+		} else if i >= total-2000 {
+			late[val]++
+		}
+	}
+	// initial distribution is 4/7, 2/7, 1/7.
+	if r := float64(early["a"]) / 2000; r < 0.5 {
+		t.Fatalf("expected early records skewed towards a, got %v", early)
+	}
+	for _, val := range []string{"a", "b", "c"} {
+		if r := float64(late[val]) / 2000; math.Abs(r-1.0/3) > 0.05 {
+			t.Fatalf("expected late records near uniform, got %v", late)
+		}
+	}
+}
+
+func TestTestid(t *testing.T) {
+	text := `s : (testid "T" 12).`
+	re := regexp.MustCompile(`^T[0-9]{13}$`)
+	for _, out := range evalText(t, text, 7700, 100) {
+		if !re.MatchString(out) {
+			t.Fatalf("unexpected id %q", out)
+		}
+		// luhn check over the digits, including the check digit.
+		sum, digits := 0, out[1:]
+		for i := len(digits) - 1; i >= 0; i-- {
+			d := int(digits[i] - '0')
+			if (len(digits)-1-i)%2 == 1 {
+				if d *= 2; d > 9 {
+					d -= 9
+				}
+			}
+			sum += d
+		}
+		if sum%10 != 0 {
+			t.Fatalf("%v fails luhn check", out)
+		}
+	}
+	checkSeeded(t, text, 7700)
+}
+
+func TestBagseq(t *testing.T) {
+	text := `s : (bagseq "names").`
+	names := []string{"alice", "bob", "carol", "dave", "eve"}
+	for i, out := range evalText(t, text, 7800, 12) {
+		if out != names[i%len(names)] {
+			t.Fatalf("expected %v at %v, got %v", names[i%len(names)], i, out)
+		}
+	}
+}
+
+func TestRecencybag(t *testing.T) {
+	text := `s : (recencybag "names" 0 1).`
+	counts, n := make(map[string]int), 10000
+	for _, out := range evalText(t, text, 7900, n) {
+		counts[out]++
+	}
+	// weights 1/16, 1/8, 1/4, 1/2, 1 normalized by 31/16.
+	names := []string{"alice", "bob", "carol", "dave", "eve"}
+	for i, name := range names {
+		ratio := math.Pow(0.5, float64(4-i)) * 16 / 31
+		if r := float64(counts[name]) / float64(n); math.Abs(r-ratio) > 0.02 {
+			t.Fatalf("expected %v at rate %v, got %v", name, ratio, r)
+		} else if i > 0 && counts[name] <= counts[names[i-1]] {
+			t.Fatalf("expected later rows more often, got %v", counts)
+		}
+	}
+	checkSeeded(t, text, 7900)
+}
+
+func TestDualformat(t *testing.T) {
+	text := `s : (dualformat (rangef 0.0 1000.0) "price" "$%.2f") "|" $price.`
+	re := regexp.MustCompile(`^\$[0-9]+\.[0-9]{2}$`)
+	for _, out := range evalText(t, text, 8000, 50) {
+		parts := strings.Split(out, "|")
+		raw, err := strconv.ParseFloat(parts[1], 64)
+		if err != nil {
+			t.Fatal(err)
+		} else if !re.MatchString(parts[0]) {
+			t.Fatalf("unexpected display %q", parts[0])
+		} else if fmt.Sprintf("$%.2f", raw) != parts[0] {
+			t.Fatalf("display %q does not match raw %v", parts[0], parts[1])
+		} else if parts[1] == parts[0][1:] {
+			t.Fatalf("expected raw value to be unformatted, got %v", parts[1])
+		}
+	}
+	checkSeeded(t, text, 8000)
+}
+
+func TestFileseq(t *testing.T) {
+	dir, err := ioutil.TempDir("", "monster")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	cursorfile := filepath.Join(dir, "cursor")
+	text := fmt.Sprintf(`s : (fileseq %q "a" "b" "c").`, cursorfile)
+	outs := append(evalText(t, text, 8100, 4), evalText(t, text, 8100, 4)...)
+	if s := strings.Join(outs, ""); s != "abcabcab" {
+		t.Fatalf("expected sequence to continue across runs, got %v", s)
+	}
+	if err := ioutil.WriteFile(cursorfile, []byte("garbage"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if s := strings.Join(evalText(t, text, 8100, 2), ""); s != "ab" {
+		t.Fatalf("expected corrupt cursor to restart, got %v", s)
+	}
+}
+
+func TestBagmapf(t *testing.T) {
+	text := `s : (bagmapf "cities.csv" 1 "upper").`
+	upper := map[string]bool{
+		"SAN FRANCISCO": true, "LOS ANGELES": true, "NEW YORK": true,
+		"BUFFALO": true, "AUSTIN": true,
+	}
+	for _, out := range evalText(t, text, 7900, 50) {
+		if !upper[out] {
+			t.Fatalf("unexpected %q", out)
+		}
+	}
+	checkSeeded(t, text, 7900)
+	testcases := map[string]map[string]bool{
+		"lower": {"  hello, world!  ": true, "  go  lang ": true, "monster": true},
+		"trim":  {"Hello, World!": true, "Go  Lang": true, "Monster": true},
+		"slug":  {"hello-world": true, "go-lang": true, "monster": true},
+	}
+	for transform, outs := range testcases {
+		text := fmt.Sprintf(`s : (bagmapf "titles.csv" 1 "%v").`, transform)
+		for _, out := range evalText(t, text, 7900, 30) {
+			if !outs[out] {
+				t.Fatalf("unexpected %q for %v", out, transform)
+			}
+		}
+	}
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatalf("expected panic for unknown transform")
+			}
+		}()
+		scope := compileText(t, ``, 7900)
+		builtin.Bagmapf(scope, "titles.csv", int64(1), "title")
+	}()
+}
+
+func TestCoverchoice(t *testing.T) {
+	text := `s : (coverchoice 6 "a" "b" "c" "d" "e" "f").`
+	outs := evalText(t, text, 8000, 60)
+	for i := 0; i < len(outs); i += 6 {
+		seen := make(map[string]bool)
+		for _, out := range outs[i : i+6] {
+			seen[out] = true
+		}
+		if len(seen) != 6 {
+			t.Fatalf("expected all options in %v", outs[i:i+6])
+		}
+	}
+	text = `s : (coverchoice 100 "a" "b" "c" "d").`
+	outs = evalText(t, text, 8000, 100)
+	seen := make(map[string]bool)
+	for _, out := range outs[:4] {
+		seen[out] = true
+	}
+	if len(seen) != 4 {
+		t.Fatalf("expected options cycled first, got %v", outs[:4])
+	}
+	checkSeeded(t, text, 8000)
+	// options joined alike keep separate state.
+	scope := compileText(t, ``, 8000)
+	builtin.Coverchoice(scope, int64(10), "a,b", "c")
+	seen = make(map[string]bool)
+	for i := 0; i < 2; i++ {
+		seen[builtin.Coverchoice(scope, int64(10), "a", "b,c").(string)] = true
+	}
+	if len(seen) != 2 {
+		t.Fatalf("expected both options covered, got %v", seen)
+	}
+}
+
+func TestHier3(t *testing.T) {
+	text := `s : (hier3 "regions.csv" 0 1 2) "|" $level1 "," $level2 "," $level3.`
+	rows := map[string]bool{
+		"EU/France/Paris|EU,France,Paris": true, "EU/France/Lyon|EU,France,Lyon": true,
+		"EU/Germany/Berlin|EU,Germany,Berlin": true, "NA/USA/Austin|NA,USA,Austin": true,
+		"NA/USA/Boston|NA,USA,Boston": true, "NA/Canada/Toronto|NA,Canada,Toronto": true,
+		"AS/Japan/Tokyo|AS,Japan,Tokyo": true,
+	}
+	seen := make(map[string]bool)
+	for _, out := range evalText(t, text, 8100, 100) {
+		if !rows[out] {
+			t.Fatalf("levels not from the same row %q", out)
+		}
+		seen[out] = true
+	}
+	if len(seen) < 2 {
+		t.Fatalf("expected rows to differ across documents, got %v", seen)
+	}
+	checkSeeded(t, text, 8100)
+	text = `s : (hier3 "regions.csv" 2 1 0 " > ").`
+	for _, out := range evalText(t, text, 8100, 20) {
+		if len(strings.Split(out, " > ")) != 3 {
+			t.Fatalf("unexpected path %q", out)
+		}
+	}
+}
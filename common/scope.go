@@ -3,7 +3,10 @@
 package common
 
 import "fmt"
+import "io"
+import "log"
 import "math/rand"
+import "strings"
 import "github.com/prataprc/goparsec"
 
 var _ = fmt.Sprintf("dummy")
@@ -32,15 +35,19 @@ func NewScopeFromRoot(ns []parsec.ParsecNode) Scope {
 }
 
 // RebuildContext to evaluate same generation tree multiple times.
+// Global variables prefixed with `_`, like _bagdir, _random and
+// run-level state maintained by builtins, are carried over to the
+// new context.
 func (scope Scope) RebuildContext() Scope {
 	newscope := scope.Clone()
 	newscope["_weights"] = make(map[string]float64)
-	globals := scope["_globals"].(Scope)
-	newscope["_globals"] = Scope{
-		"_bagdir":   globals["_bagdir"],
-		"_prodfile": globals["_prodfile"],
-		"_random":   globals["_random"],
+	globals, newglobals := scope["_globals"].(Scope), make(Scope)
+	for name, value := range globals {
+		if strings.HasPrefix(name, "_") {
+			newglobals[name] = value
+		}
 	}
+	newscope["_globals"] = newglobals
 	return newscope.applyGlobalForms()
 }
 
@@ -77,6 +84,33 @@ func (scope Scope) GetRandom() *rand.Rand {
 	return (scope["_globals"].(Scope))["_random"].(*rand.Rand)
 }
 
+// SetDebugWriter will set the io.Writer to log debug information,
+// like the selections made by `debugchoice` form.
+func (scope Scope) SetDebugWriter(w io.Writer) Scope {
+	(scope["_globals"].(Scope))["_debugwriter"] = w
+	return scope
+}
+
+// GetDebugWriter will return the current debug io.Writer, if any.
+func (scope Scope) GetDebugWriter() (w io.Writer, ok bool) {
+	w, ok = (scope["_globals"].(Scope))["_debugwriter"].(io.Writer)
+	return w, ok
+}
+
+// SetLogger will set the logger for warnings, like a non-terminal
+// shadowing a builtin, that are otherwise logged using the standard
+// logger. Pass a logger writing to ioutil.Discard to silence them.
+func (scope Scope) SetLogger(logger *log.Logger) Scope {
+	(scope["_globals"].(Scope))["_logger"] = logger
+	return scope
+}
+
+// GetLogger will return the logger for warnings, if any.
+func (scope Scope) GetLogger() (logger *log.Logger, ok bool) {
+	logger, ok = (scope["_globals"].(Scope))["_logger"].(*log.Logger)
+	return logger, ok
+}
+
 // SetWeight will set the weightage for form `name`.
 func (scope Scope) SetWeight(name string, value float64) Scope {
 	w := scope["_weights"].(map[string]float64)
@@ -165,6 +199,25 @@ func (scope Scope) Clone() Scope {
 	return newS
 }
 
+// CountSelection will increment the count of `option` selected
+// under `label`. Counts are maintained in global scope and are
+// carried over across RebuildContext.
+func CountSelection(scope Scope, label string, option string) {
+	counts := SelectionCounts(scope, label)
+	if counts == nil {
+		counts = make(map[string]int64)
+		scope.Set("_counted:"+label, counts, true /*global*/)
+	}
+	counts[option]++
+}
+
+// SelectionCounts will return the count of each option selected
+// under `label`, or nil if nothing was selected yet.
+func SelectionCounts(scope Scope, label string) map[string]int64 {
+	counts, _ := (scope["_globals"].(Scope))["_counted:"+label].(map[string]int64)
+	return counts
+}
+
 //----------------
 // local functions
 //----------------
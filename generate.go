@@ -0,0 +1,306 @@
+//  Copyright (c) 2013 Couchbase, Inc.
+
+package monster
+
+import "context"
+import "fmt"
+import "log"
+import "math/rand"
+import "runtime"
+import "strings"
+import "time"
+
+import "github.com/prataprc/goparsec"
+import "github.com/prataprc/monster/common"
+
+// ParseError is returned when a production grammar fails to parse.
+// Pos is the byte offset into the grammar where parsing stopped,
+// Line and Col are the same position, both starting from 1.
+type ParseError struct {
+	Pos       int
+	Line, Col int
+	Msg       string
+}
+
+// nodeError is raised by node builders, while compiling the grammar,
+// for the construct at byte offset pos.
+type nodeError struct {
+	pos int
+	err error
+}
+
+// Error implement error interface.
+func (err *nodeError) Error() string {
+	return err.err.Error()
+}
+
+// trackScanner remembers the furthest position matched by the parser,
+// which is where a syntax error is located when parsing fails.
+type trackScanner struct {
+	parsec.Scanner
+	furthest *int
+}
+
+// Match implement parsec.Scanner interface.
+func (s trackScanner) Match(pattern string) ([]byte, parsec.Scanner) {
+	m, next := s.Scanner.Match(pattern)
+	return m, s.track(next)
+}
+
+// SkipWS implement parsec.Scanner interface.
+func (s trackScanner) SkipWS() ([]byte, parsec.Scanner) {
+	m, next := s.Scanner.SkipWS()
+	return m, s.track(next)
+}
+
+// SetWSPattern implement parsec.Scanner interface.
+func (s trackScanner) SetWSPattern(pattern string) parsec.Scanner {
+	return s.track(s.Scanner.SetWSPattern(pattern))
+}
+
+// TrackLineno implement parsec.Scanner interface.
+func (s trackScanner) TrackLineno() parsec.Scanner {
+	return s.track(s.Scanner.TrackLineno())
+}
+
+// Clone implement parsec.Scanner interface.
+func (s trackScanner) Clone() parsec.Scanner {
+	return s.track(s.Scanner.Clone())
+}
+
+// MatchString implement parsec.Scanner interface.
+func (s trackScanner) MatchString(str string) (bool, parsec.Scanner) {
+	ok, next := s.Scanner.MatchString(str)
+	return ok, s.track(next)
+}
+
+// SubmatchAll implement parsec.Scanner interface.
+func (s trackScanner) SubmatchAll(
+	pattern string) (map[string][]byte, parsec.Scanner) {
+
+	m, next := s.Scanner.SubmatchAll(pattern)
+	return m, s.track(next)
+}
+
+// SkipAny implement parsec.Scanner interface.
+func (s trackScanner) SkipAny(pattern string) ([]byte, parsec.Scanner) {
+	m, next := s.Scanner.SkipAny(pattern)
+	return m, s.track(next)
+}
+
+func (s trackScanner) track(next parsec.Scanner) parsec.Scanner {
+	if cursor := next.GetCursor(); cursor > *s.furthest {
+		*s.furthest = cursor
+	}
+	return trackScanner{next, s.furthest}
+}
+
+// untracked will adapt parser `p`, that expects the scanner created
+// by parsec.NewScanner, like parsec.String(), to trackScanner.
+func untracked(p parsec.Parser) parsec.Parser {
+	return func(s parsec.Scanner) (parsec.ParsecNode, parsec.Scanner) {
+		if ts, ok := s.(trackScanner); ok {
+			node, next := p(ts.Scanner)
+			return node, ts.track(next)
+		}
+		return p(s)
+	}
+}
+
+func newParseError(text string, pos int, msg string) *ParseError {
+	if pos > len(text) {
+		pos = len(text)
+	}
+	line := strings.Count(text[:pos], "\n") + 1
+	col := pos - strings.LastIndex(text[:pos], "\n")
+	return &ParseError{Pos: pos, Line: line, Col: col, Msg: msg}
+}
+
+// Error implement error interface.
+func (err *ParseError) Error() string {
+	return fmt.Sprintf("parse error at line %v col %v: %v", err.Line, err.Col, err.Msg)
+}
+
+// Generator is a production grammar compiled once, to generate
+// records repeatedly without parsing the grammar again. Bags read
+// while generating are cached for the life of the generator.
+type Generator struct {
+	Root   string // non-terminal to evaluate, defaults to "s".
+	scope  common.Scope
+	nterms common.NTForms
+}
+
+// Compile will parse production grammar `text` and return a Generator
+// for it, bags are located relative to `bagdir` or `prodfile`.
+func Compile(text string, bagdir, prodfile string) (*Generator, error) {
+	scope, err := Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	BuildContext(scope, 0, bagdir, prodfile)
+	gen := &Generator{
+		Root:   "s",
+		scope:  scope,
+		nterms: scope["_nonterminals"].(common.NTForms),
+	}
+	return gen, nil
+}
+
+// Next will build a fresh context seeded with `seed`, evaluate the
+// root non-terminal and return the generated record. Same seed
+// generates the same record. Evaluation failures panic.
+func (gen *Generator) Next(seed uint64) string {
+	forms, ok := gen.nterms[gen.Root]
+	if !ok {
+		panic(fmt.Errorf("unknown non-terminal %q\n", gen.Root))
+	}
+	scope := gen.scope.Clone()
+	scope["_globals"] = gen.scope["_globals"].(common.Scope).Clone()
+	if seed == 0 {
+		seed = uint64(time.Now().UnixNano())
+	}
+	scope.SetRandom(rand.New(rand.NewSource(int64(seed))))
+	scope = scope.RebuildContext()
+	return fmt.Sprintf("%v", EvalForms("root", scope, forms))
+}
+
+// GenerateOne will compile production grammar `text`, build a
+// context for it and evaluate non-terminal `root` once, returning
+// the generated record. Parse and evaluation failures are returned
+// as error.
+func GenerateOne(
+	text string,
+	seed uint64,
+	bagdir, prodfile, root string) (out string, err error) {
+
+	scope, err := Parse(text)
+	if err != nil {
+		return "", err
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+	scope = BuildContext(scope, seed, bagdir, prodfile)
+	forms, ok := scope.GetNonTerminal(root)
+	if !ok {
+		return "", fmt.Errorf("unknown non-terminal %q", root)
+	}
+	return fmt.Sprintf("%v", EvalForms("root", scope, forms)), nil
+}
+
+// GenerateN will evaluate non-terminal `root` from `nterms`, `count`
+// times, rebuilding the context of scope before each evaluation.
+// Records are streamed on the returned channel, which is closed once
+// `count` records are sent. A panic while evaluating is logged, using
+// the scope's logger if set, and closes the channel early, callers
+// can detect the failure by counting the records received. Use
+// GenerateCtx to get the failure as error.
+func GenerateN(
+	scope common.Scope,
+	nterms common.NTForms, root string, count int) <-chan string {
+
+	logf := log.Printf
+	if logger, ok := scope.GetLogger(); ok {
+		logf = logger.Printf
+	}
+	outch := make(chan string)
+	go func() {
+		defer close(outch)
+		defer func() {
+			if r := recover(); r != nil {
+				logf("error: generating %v: %v", root, r)
+			}
+		}()
+		for i := 0; i < count; i++ {
+			scope = scope.RebuildContext()
+			val := EvalForms("root", scope, nterms[root])
+			outch <- fmt.Sprintf("%v", val)
+		}
+	}()
+	return outch
+}
+
+// GenerateCtx will evaluate non-terminal `root` from `nterms`,
+// rebuilding the context of scope before each evaluation, and send
+// the records on `out` until the receiver closes `out`, or ctx is
+// cancelled. It returns nil once `out` is closed, and ctx.Err() once
+// cancelled, even when blocked on `out`. Evaluation failures are
+// returned as error. Prefer cancelling ctx to stop a running
+// generator, closing `out` while it is sending is racy.
+func GenerateCtx(
+	ctx context.Context,
+	scope common.Scope,
+	nterms common.NTForms, root string, out chan<- string) (err error) {
+
+	defer func() {
+		if r := recover(); r != nil {
+			if isClosedSend(r) { // receiver is done with the records.
+				err = nil
+				return
+			}
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		scope = scope.RebuildContext()
+		val := EvalForms("root", scope, nterms[root])
+		select {
+		case out <- fmt.Sprintf("%v", val):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// isClosedSend will check whether `r`, recovered from a panic, is
+// due to sending on a closed channel.
+func isClosedSend(r interface{}) bool {
+	rerr, ok := r.(runtime.Error)
+	return ok && strings.Contains(rerr.Error(), "send on closed channel")
+}
+
+// Parse will compile production grammar `text` into a scope, without
+// building a context or generating. Syntax errors, and panics while
+// constructing forms, are returned as *ParseError instead of
+// crashing the caller.
+func Parse(text string) (scope common.Scope, err error) {
+	furthest := 0
+	s := parsec.Scanner(trackScanner{parsec.NewScanner([]byte(text)), &furthest})
+	defer func() {
+		if r := recover(); r != nil {
+			pos := furthest
+			if nerr, ok := r.(*nodeError); ok {
+				pos = nerr.pos
+			}
+			msg := strings.TrimSpace(fmt.Sprintf("%v", r))
+			scope, err = nil, newParseError(text, pos, msg)
+		}
+	}()
+
+	node, s := Y(s)
+	scope, ok := node.(common.Scope)
+	if _, s = s.SkipWS(); !ok || !s.Endof() {
+		return nil, newParseError(text, furthest, unexpected(text, furthest))
+	}
+	return scope, nil
+}
+
+// unexpected will describe the input at byte offset pos, where
+// parsing failed.
+func unexpected(text string, pos int) string {
+	fields := strings.Fields(text[pos:])
+	if len(fields) == 0 {
+		return "unexpected end of input"
+	}
+	tok := fields[0]
+	if len(tok) > 16 {
+		tok = tok[:16] + "..."
+	}
+	return fmt.Sprintf("unexpected input %q", tok)
+}
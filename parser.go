@@ -34,7 +34,7 @@
 // forms["##literaltok"] evaluating INT/HEX/OCT/FLOAT/TRUE/FALSE in form-args
 // forms["##formtok"] evaluating any other form-args, an open ended spec
 // forms["##ident"] evaluating non-terminal identifiers in rule-args
-// forms["##term"] evaluating terminals like DQ, NL in farm-args or rule-args
+// forms["##term"] evaluating terminals like DQ, NL, TAB, CR, SP in farm-args or rule-args
 // forms["##string"] evaluating literal strings in form-args and rule-args
 // forms["##ref"] evaluating references into local/global namespace
 // forms["##rule"] evaluating a non-terminal rule
@@ -59,6 +59,10 @@
 //  $<symbol> specified in a rule or,
 //  #<symbol> specified in a form argument,
 //      will evaluate a lookup into local or global scope.
+//  $_ will evaluate to the intermediate value within a `pipe` form.
+//
+// lazy builtins, like `pipe` and `nested`, receive their arguments as un-evaluated
+// forms and are responsible for evaluating them.
 //
 // Programmatically invoking monster {
 //
@@ -70,12 +74,27 @@
 //        val := monster.EvalForms("root", scope, nterms["s"])
 //    }
 // }
+//
+// or, to stream records,
+//
+//    for val := range monster.GenerateN(scope, nterms, "s", count) {
+//    }
+//
+// or, to generate a single record,
+//
+//    out, err := monster.GenerateOne(text, seed, bagdir, prodfile, "s")
+//
+// or, to compile once and generate records repeatedly,
+//
+//    gen, err := monster.Compile(text, bagdir, prodfile)
+//    val := gen.Next(seed)
 package monster
 
 import "fmt"
 import "log"
 import "time"
 import "strconv"
+import "regexp"
 import "math/rand"
 
 import "github.com/prataprc/goparsec"
@@ -97,13 +116,13 @@ var Y parsec.Parser
 // Terminal rats
 var formtok = parsec.Token(`[^ \t\r\n\(\)]+`, "FORMTOK")
 var ident = parsec.Token(`[a-z0-9]+`, "IDENT")
-var ref = parsec.Token(`[$#][a-z0-9]+`, "REF")
+var ref = parsec.Token(`[$#]([a-z0-9]+|_\b)`, "REF")
 var term = parsec.Token(`[A-Z][A-Z0-9]*`, "TERM")
-var sTring = parsec.String()
+var sTring = untracked(parsec.String())
 var literaltok = parsec.OrdChoice(
 	litNode,
 	parsec.Float(), parsec.Hex(), parsec.Oct(), parsec.Int(),
-	parsec.String(),
+	untracked(parsec.String()),
 	parsec.Token(`true`, "TRUE"), parsec.Token(`false`, "FALSE"))
 var openparan = parsec.Token(`\(`, "OPENPARAN")
 var closeparan = parsec.Token(`\)`, "CLOSEPARAN")
@@ -140,6 +159,8 @@ func init() {
 //      _bagdir:       absolute path to directory containing bags of data
 //      _prodfile:     absolute path to production file
 //      _random:       reference to seeded *math.rand.Rand object
+//      _bagcache:     bags read while evaluating this context
+//      _logger:       optional *log.Logger for warnings, refer SetLogger
 func BuildContext(
 	scope common.Scope,
 	seed uint64,
@@ -147,6 +168,7 @@ func BuildContext(
 
 	scope["_prodfile"] = prodfile
 	scope.SetBagdir(bagdir)
+	builtin.NewBagCache(scope)
 	if seed != 0 {
 		scope.SetRandom(rand.New(rand.NewSource(int64(seed))))
 	} else {
@@ -155,9 +177,13 @@ func BuildContext(
 	}
 	// verify conflicts between user provided form-names
 	// and builtin form-names.
+	logf := log.Printf
+	if logger, ok := scope.GetLogger(); ok {
+		logf = logger.Printf
+	}
 	for name := range scope["_nonterminals"].(common.NTForms) {
 		if _, ok := builtins[name]; ok {
-			log.Printf("warn: `%v` non-terminal is defined as builtin\n", name)
+			logf("warn: `%v` non-terminal is defined as builtin\n", name)
 		}
 	}
 	return scope.RebuildContext()
@@ -179,7 +205,17 @@ func formNode(ns []parsec.ParsecNode) parsec.ParsecNode {
 	name := ns[1].(*parsec.Terminal).Value
 	ns = ns[2].([]parsec.ParsecNode)
 	form, ok := builtins[name]
-	if ok { // apply builtin form.
+	if ok && lazybuiltins[name] { // apply lazy builtin form.
+		return common.NewForm(
+			name,
+			func(scope common.Scope, _ ...interface{}) interface{} {
+				args := make([]interface{}, 0, len(ns))
+				for _, n := range ns {
+					args = append(args, n)
+				}
+				return form.Eval(scope, args...)
+			})
+	} else if ok { // apply builtin form.
 		return common.NewForm(
 			name,
 			func(scope common.Scope, _ ...interface{}) interface{} {
@@ -386,25 +422,29 @@ func litNode(ns []parsec.ParsecNode) parsec.ParsecNode {
 	case "INT":
 		val, err = strconv.ParseInt(t.Value, 10, 64)
 		if err != nil {
-			panic(fmt.Errorf("cannot parse %v for integer\n", t))
+			fmsg := "cannot parse %v for integer\n"
+			panic(&nodeError{t.Position, fmt.Errorf(fmsg, t.Value)})
 		}
 
 	case "HEX":
 		val, err = strconv.ParseInt(t.Value, 16, 64)
 		if err != nil {
-			panic(fmt.Errorf("cannot parse %v for hexadecimal\n", t))
+			fmsg := "cannot parse %v for hexadecimal\n"
+			panic(&nodeError{t.Position, fmt.Errorf(fmsg, t.Value)})
 		}
 
 	case "OCT":
 		val, err = strconv.ParseInt(t.Value, 8, 64)
 		if err != nil {
-			panic(fmt.Errorf("cannot parse %v for octal\n", t))
+			fmsg := "cannot parse %v for octal\n"
+			panic(&nodeError{t.Position, fmt.Errorf(fmsg, t.Value)})
 		}
 
 	case "FLOAT":
 		val, err = strconv.ParseFloat(t.Value, 64)
 		if err != nil {
-			panic(fmt.Errorf("cannot parse %v for float64\n", t))
+			fmsg := "cannot parse %v for float64\n"
+			panic(&nodeError{t.Position, fmt.Errorf(fmsg, t.Value)})
 		}
 
 	case "STRING":
@@ -433,8 +473,25 @@ func one2one(ns []parsec.ParsecNode) parsec.ParsecNode {
 //--------------------
 
 var builtins = make(map[string]*common.Form)
+var lazybuiltins = make(map[string]bool)
 var literals = make(map[string]string)
 
+var identRe = regexp.MustCompile(`^[a-z0-9]+$`)
+
+// RegisterBuiltin will add an application supplied form `name`,
+// evaluated by `fn` with its arguments evaluated. It shall be called
+// before compiling production grammars that use it, typically from
+// init(). Registering an existing builtin will panic. Non-terminals
+// shadowing the builtin are warned by BuildContext.
+func RegisterBuiltin(name string, fn common.FormFn) {
+	if !identRe.MatchString(name) {
+		panic(fmt.Errorf("builtin name %q must match %v\n", name, identRe))
+	} else if _, ok := builtins[name]; ok {
+		panic(fmt.Errorf("builtin %q is already registered\n", name))
+	}
+	builtins[name] = common.NewForm(name, fn)
+}
+
 func initBuiltins() {
 	builtins["let"] = common.NewForm("let", builtin.Let)
 	builtins["letr"] = common.NewForm("letr", builtin.Letr)
@@ -450,9 +507,120 @@ func initBuiltins() {
 	builtins["dec"] = common.NewForm("dec", builtin.Dec)
 	builtins["len"] = common.NewForm("len", builtin.Len)
 	builtins["sprintf"] = common.NewForm("sprintf", builtin.Sprintf)
+	builtins["histogram"] = common.NewForm("histogram", builtin.Histogram)
+	builtins["jbagkey"] = common.NewForm("jbagkey", builtin.Jbagkey)
+	builtins["jbagval"] = common.NewForm("jbagval", builtin.Jbagval)
+	builtins["pipe"] = common.NewForm("pipe", builtin.Pipe)
+	lazybuiltins["pipe"] = true
+	builtins["lognormal"] = common.NewForm("lognormal", builtin.Lognormal)
+	builtins["wbagfast"] = common.NewForm("wbagfast", builtin.Wbagfast)
+	builtins["norepeat"] = common.NewForm("norepeat", builtin.Norepeat)
+	builtins["bagsample"] = common.NewForm("bagsample", builtin.Bagsample)
+	builtins["localenum"] = common.NewForm("localenum", builtin.Localenum)
+	builtins["businesstime"] = common.NewForm("businesstime", builtin.Businesstime)
+	builtins["fullname"] = common.NewForm("fullname", builtin.Fullname)
+	builtins["smoothbag"] = common.NewForm("smoothbag", builtin.Smoothbag)
+	builtins["debugchoice"] = common.NewForm("debugchoice", builtin.Debugchoice)
+	builtins["benford"] = common.NewForm("benford", builtin.Benford)
+	builtins["prototext"] = common.NewForm("prototext", builtin.Prototext)
+	builtins["bagwhere"] = common.NewForm("bagwhere", builtin.Bagwhere)
+	builtins["partition"] = common.NewForm("partition", builtin.Partition)
+	builtins["password"] = common.NewForm("password", builtin.Password)
+	builtins["pareto"] = common.NewForm("pareto", builtin.Pareto)
+	builtins["bagmatch"] = common.NewForm("bagmatch", builtin.Bagmatch)
+	builtins["wranges"] = common.NewForm("wranges", builtin.Wranges)
+	builtins["perm"] = common.NewForm("perm", builtin.Perm)
+	builtins["targetrate"] = common.NewForm("targetrate", builtin.Targetrate)
+	builtins["remember"] = common.NewForm("remember", builtin.Remember)
+	builtins["recall"] = common.NewForm("recall", builtin.Recall)
+	builtins["diurnaltime"] = common.NewForm("diurnaltime", builtin.Diurnaltime)
+	builtins["wsum"] = common.NewForm("wsum", builtin.Wsum)
+	builtins["bagquota"] = common.NewForm("bagquota", builtin.Bagquota)
+	builtins["nested"] = common.NewForm("nested", builtin.Nested)
+	lazybuiltins["nested"] = true
+	builtins["mixture"] = common.NewForm("mixture", builtin.Mixture)
+	builtins["bagf"] = common.NewForm("bagf", builtin.Bagf)
+	builtins["sessionlen"] = common.NewForm("sessionlen", builtin.Sessionlen)
+	builtins["upper"] = common.NewForm("upper", builtin.Upper)
+	builtins["lower"] = common.NewForm("lower", builtin.Lower)
+	builtins["interval"] = common.NewForm("interval", builtin.Interval)
+	builtins["intervalend"] = common.NewForm("intervalend", builtin.Intervalend)
+	builtins["replace"] = common.NewForm("replace", builtin.Replace)
+	builtins["localebag"] = common.NewForm("localebag", builtin.Localebag)
+	builtins["substr"] = common.NewForm("substr", builtin.Substr)
+	builtins["multibag"] = common.NewForm("multibag", builtin.Multibag)
+	builtins["repeat"] = common.NewForm("repeat", builtin.Repeat)
+	builtins["progression"] = common.NewForm("progression", builtin.Progression)
+	builtins["hostname"] = common.NewForm("hostname", builtin.Hostname)
+	builtins["add"] = common.NewForm("add", builtin.Add)
+	builtins["sub"] = common.NewForm("sub", builtin.Sub)
+	builtins["mul"] = common.NewForm("mul", builtin.Mul)
+	builtins["div"] = common.NewForm("div", builtin.Div)
+	builtins["mod"] = common.NewForm("mod", builtin.Mod)
+	builtins["bagslice"] = common.NewForm("bagslice", builtin.Bagslice)
+	builtins["index"] = common.NewForm("index", builtin.Index)
+	builtins["join"] = common.NewForm("join", builtin.Join)
+	builtins["min"] = common.NewForm("min", builtin.Min)
+	builtins["max"] = common.NewForm("max", builtin.Max)
+	builtins["geometric"] = common.NewForm("geometric", builtin.Geometric)
+	builtins["seq"] = common.NewForm("seq", builtin.Seq)
+	builtins["natstr"] = common.NewForm("natstr", builtin.Natstr)
+	builtins["randfloat"] = common.NewForm("randfloat", builtin.Randfloat)
+	builtins["iban"] = common.NewForm("iban", builtin.Iban)
+	builtins["thresholdbool"] = common.NewForm("thresholdbool", builtin.Thresholdbool)
+	builtins["zipf"] = common.NewForm("zipf", builtin.Zipf)
+	builtins["bursttime"] = common.NewForm("bursttime", builtin.Bursttime)
+	builtins["randdate"] = common.NewForm("randdate", builtin.Randdate)
+	builtins["bagshard"] = common.NewForm("bagshard", builtin.Bagshard)
+	builtins["epoch"] = common.NewForm("epoch", builtin.Epoch)
+	builtins["bivariate"] = common.NewForm("bivariate", builtin.Bivariate)
+	builtins["invcdf"] = common.NewForm("invcdf", builtin.Invcdf)
+	builtins["email"] = common.NewForm("email", builtin.Email)
+	builtins["randipv4"] = common.NewForm("randipv4", builtin.IPv4)
+	builtins["randipv6"] = common.NewForm("randipv6", builtin.IPv6)
+	builtins["counted"] = common.NewForm("counted", builtin.Counted)
+	builtins["dynwchoice"] = common.NewForm("dynwchoice", builtin.Dynwchoice)
+	builtins["shuffle"] = common.NewForm("shuffle", builtin.Shuffle)
+	builtins["bagjoin"] = common.NewForm("bagjoin", builtin.Bagjoin)
+	builtins["driftchoice"] = common.NewForm("driftchoice", builtin.Driftchoice)
+	builtins["wchoice"] = common.NewForm("wchoice", builtin.Wchoice)
+	builtins["bagn"] = common.NewForm("bagn", builtin.Bagn)
+	builtins["stratabag"] = common.NewForm("stratabag", builtin.Stratabag)
+	builtins["bagrow"] = common.NewForm("bagrow", builtin.Bagrow)
+	builtins["condbag"] = common.NewForm("condbag", builtin.Condbag)
+	builtins["jbag"] = common.NewForm("jbag", builtin.Jbag)
+	builtins["budget"] = common.NewForm("budget", builtin.Budget)
+	builtins["bagu"] = common.NewForm("bagu", builtin.Bagu)
+	builtins["checkid"] = common.NewForm("checkid", builtin.Checkid)
+	builtins["bagexcept"] = common.NewForm("bagexcept", builtin.Bagexcept)
+	builtins["annealchoice"] = common.NewForm("annealchoice", builtin.Annealchoice)
+	builtins["testid"] = common.NewForm("testid", builtin.Testid)
+	builtins["bagseq"] = common.NewForm("bagseq", builtin.Bagseq)
+	builtins["recencybag"] = common.NewForm("recencybag", builtin.Recencybag)
+	builtins["dualformat"] = common.NewForm("dualformat", builtin.Dualformat)
+	builtins["fileseq"] = common.NewForm("fileseq", builtin.Fileseq)
+	builtins["bagmapf"] = common.NewForm("bagmapf", builtin.Bagmapf)
+	builtins["coverchoice"] = common.NewForm("coverchoice", builtin.Coverchoice)
+	builtins["hier3"] = common.NewForm("hier3", builtin.Hier3)
+	builtins["trim"] = common.NewForm("trim", builtin.Trim)
+}
+
+var termRe = regexp.MustCompile(`^[A-Z][A-Z0-9]*$`)
+
+// RegisterLiteral will define terminal `term` to evaluate to
+// `value`, like DQ and NL. It shall be called before compiling
+// production grammars that use it.
+func RegisterLiteral(term, value string) {
+	if !termRe.MatchString(term) {
+		panic(fmt.Errorf("literal name %q must match %v\n", term, termRe))
+	}
+	literals[term] = value
 }
 
 func initLiterals() {
 	literals["DQ"] = "\""
 	literals["NL"] = "\n"
+	literals["TAB"] = "\t"
+	literals["CR"] = "\r"
+	literals["SP"] = " "
 }
@@ -5,6 +5,11 @@ package monster
 import "testing"
 import "fmt"
 import "io/ioutil"
+import "bytes"
+import "context"
+import "log"
+import "os"
+import "strings"
 import "time"
 
 import "github.com/prataprc/goparsec"
@@ -378,3 +383,261 @@ func BenchmarkJSONProd(b *testing.B) {
 	}
 	b.SetBytes(int64(float64(out) / float64(b.N)))
 }
+
+func TestGenerateOne(t *testing.T) {
+	text := `s : "hello " (sprintf "%v" 10) (choice "" "") ident.
+             ident : "-" (upper "world").`
+	out, err := GenerateOne(text, 100, "./testdata", "", "s")
+	if err != nil {
+		t.Fatal(err)
+	} else if out != "hello 10-WORLD" {
+		t.Fatalf("unexpected output %q", out)
+	}
+	text = `s : (range 0 1000000).`
+	out1, err1 := GenerateOne(text, 100, "./testdata", "", "s")
+	out2, err2 := GenerateOne(text, 100, "./testdata", "", "s")
+	if err1 != nil || err2 != nil {
+		t.Fatal(err1, err2)
+	} else if out1 != out2 {
+		t.Fatalf("expected same output for same seed, %v != %v", out1, out2)
+	}
+	if _, err := GenerateOne(`s : "a" (`, 100, "", "", "s"); err == nil {
+		t.Fatalf("expected parse error")
+	}
+	if _, err := GenerateOne(`s : "a".`, 100, "", "", "x"); err == nil {
+		t.Fatalf("expected error for unknown non-terminal")
+	}
+	if _, err := GenerateOne(`s : (div 1 0).`, 100, "", "", "s"); err == nil {
+		t.Fatalf("expected evaluation error")
+	}
+}
+
+func TestGenerateN(t *testing.T) {
+	text := `s : (range 0 1000000).`
+	root, err := Parse(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	scope := BuildContext(root, 100, "./testdata", "")
+	nterms := scope["_nonterminals"].(common.NTForms)
+	outs := make([]string, 0)
+	for val := range GenerateN(scope, nterms, "s", 10) {
+		outs = append(outs, val)
+	}
+	if len(outs) != 10 {
+		t.Fatalf("expected 10 records, got %v", len(outs))
+	}
+	out, err := GenerateOne(text, 100, "./testdata", "", "s")
+	if err != nil {
+		t.Fatal(err)
+	} else if outs[0] != out {
+		t.Fatalf("expected %v, got %v", out, outs[0])
+	}
+	for range GenerateN(scope, nterms, "s", 0) {
+		t.Fatalf("expected no records")
+	}
+
+	var buf bytes.Buffer
+	root, _ = Parse(`s : (div 1 0).`)
+	scope = BuildContext(root, 100, "./testdata", "")
+	scope.SetLogger(log.New(&buf, "", 0))
+	nterms = scope["_nonterminals"].(common.NTForms)
+	for range GenerateN(scope, nterms, "s", 10) {
+		t.Fatalf("expected no records on failure")
+	}
+	if !strings.Contains(buf.String(), "error: generating s") {
+		t.Fatalf("expected failure logged to scope logger, got %q", buf.String())
+	}
+}
+
+func TestGenerateCtx(t *testing.T) {
+	root, err := Parse(`s : (range 0 1000000).`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	scope := BuildContext(root, 100, "./testdata", "")
+	nterms := scope["_nonterminals"].(common.NTForms)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	outch, errch := make(chan string), make(chan error, 1)
+	go func() { errch <- GenerateCtx(ctx, scope, nterms, "s", outch) }()
+	for i := 0; i < 100; i++ {
+		<-outch
+	}
+	cancel()
+	select {
+	case err := <-errch:
+		if err != context.Canceled {
+			t.Fatalf("expected %v, got %v", context.Canceled, err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("expected generation to stop on cancel")
+	}
+
+	// receiver closing the channel completes generation.
+	donech := make(chan string)
+	close(donech)
+	if err := GenerateCtx(context.Background(), scope, nterms, "s", donech); err != nil {
+		t.Fatalf("expected nil on completion, got %v", err)
+	}
+
+	root, _ = Parse(`s : (div 1 0).`)
+	scope = BuildContext(root, 100, "./testdata", "")
+	nterms = scope["_nonterminals"].(common.NTForms)
+	err = GenerateCtx(context.Background(), scope, nterms, "s", outch)
+	if err == nil {
+		t.Fatalf("expected evaluation error")
+	}
+}
+
+func TestCompile(t *testing.T) {
+	text := `s : (range 0 1000000) "," (bagn "products.csv" 1) "," ident.
+             ident : (upper "x").`
+	gen, err := Compile(text, "./testdata", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	outs := make(map[uint64]string)
+	for seed := uint64(1); seed <= 100; seed++ {
+		outs[seed] = gen.Next(seed)
+	}
+	for seed := uint64(100); seed > 0; seed-- {
+		if out := gen.Next(seed); out != outs[seed] {
+			t.Fatalf("expected %v for seed %v, got %v", outs[seed], seed, out)
+		}
+	}
+	out, err := GenerateOne(text, 42, "./testdata", "", "s")
+	if err != nil {
+		t.Fatal(err)
+	} else if out != outs[42] {
+		t.Fatalf("expected %v, got %v", out, outs[42])
+	}
+	gen.Root = "ident"
+	if out := gen.Next(1); out != "X" {
+		t.Fatalf("expected X, got %v", out)
+	}
+	if _, err := Compile(`s : "a" (`, "", ""); err == nil {
+		t.Fatalf("expected parse error")
+	}
+}
+
+func TestParse(t *testing.T) {
+	text := `(let x 10) s : "a" $x | ident. ident : "b".`
+	if scope, err := Parse(text); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	} else if _, ok := scope.GetNonTerminal("ident"); !ok {
+		t.Fatalf("expected non-terminal ident in %v", scope)
+	} else if _, err := Parse(``); err != nil {
+		t.Fatalf("unexpected error %v for empty grammar", err)
+	}
+	testcases := []struct {
+		text      string
+		line, col int
+		msg       string
+	}{
+		{`s : "a" (`, 1, 10, "unexpected end of input"},
+		{`s : "a"`, 1, 8, "unexpected end of input"},
+		{"s : \"a\".\nt \"b\".", 2, 3, `unexpected input "\"b\"."`},
+		{"s : \"a\".\n\n  ) t : \"b\".", 3, 3, `unexpected input ")"`},
+		{"s : \"a\".\nt : \"b\" (\n   Upper \"c\").", 3, 4, `unexpected input "Upper"`},
+		{"s : \"a\".\nt : \"b\" | \"c\"\n  u : \"d\".", 3, 5, `unexpected input ":"`},
+		{"s : \"a\".\n\nt : \"b\" \"c\" ) .", 3, 13, `unexpected input ")"`},
+		{"s : \"a\".\nt : (range 99999999999999999999 1).", 2, 12, "cannot parse"},
+		{"s : \"a\".\nt : \"b\"\n  (sprintf \"%x\" 0x1FFFFFFFFFFFFFFFF).", 3, 17, "hexadecimal"},
+	}
+	for _, tcase := range testcases {
+		scope, err := Parse(tcase.text)
+		perr, ok := err.(*ParseError)
+		if !ok || scope != nil {
+			t.Fatalf("expected *ParseError for %q, got %v", tcase.text, err)
+		} else if perr.Line != tcase.line || perr.Col != tcase.col {
+			fmsg := "expected error at %v:%v for %q, got %v"
+			t.Fatalf(fmsg, tcase.line, tcase.col, tcase.text, perr)
+		} else if !strings.Contains(perr.Msg, tcase.msg) {
+			t.Fatalf("expected %q in error for %q, got %v", tcase.msg, tcase.text, perr)
+		}
+	}
+}
+
+func TestBuildContextLogger(t *testing.T) {
+	text := []byte(`s : "a" | upper. upper : "b".`)
+	build := func(setup func(common.Scope)) {
+		root, _ := Y(parsec.NewScanner(text))
+		setup(root.(common.Scope))
+		BuildContext(root.(common.Scope), 100, "./testdata", "")
+	}
+
+	var stdbuf, buf bytes.Buffer
+	log.SetOutput(&stdbuf)
+	defer log.SetOutput(os.Stderr)
+
+	build(func(common.Scope) {})
+	if !strings.Contains(stdbuf.String(), "`upper` non-terminal is defined as builtin") {
+		t.Fatalf("expected warning in standard log, got %q", stdbuf.String())
+	}
+	stdbuf.Reset()
+	build(func(scope common.Scope) { scope.SetLogger(log.New(&buf, "", 0)) })
+	if stdbuf.Len() > 0 {
+		t.Fatalf("expected nothing in standard log, got %q", stdbuf.String())
+	} else if !strings.Contains(buf.String(), "`upper` non-terminal") {
+		t.Fatalf("expected warning in redirected log, got %q", buf.String())
+	}
+	build(func(scope common.Scope) { scope.SetLogger(log.New(ioutil.Discard, "", 0)) })
+	if stdbuf.Len() > 0 {
+		t.Fatalf("expected silenced warning, got %q", stdbuf.String())
+	}
+}
+
+func TestRegisterBuiltin(t *testing.T) {
+	t.Cleanup(func() { delete(builtins, "twice") })
+	RegisterBuiltin("twice", func(scope common.Scope, args ...interface{}) interface{} {
+		return fmt.Sprintf("%v%v", args[0], args[0])
+	})
+	out, err := GenerateOne(`s : (twice "ab") (twice 10).`, 100, "", "", "s")
+	if err != nil {
+		t.Fatal(err)
+	} else if out != "abab1010" {
+		t.Fatalf("unexpected output %q", out)
+	}
+	for _, name := range []string{"twice", "bag", "Bad-Name"} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatalf("expected panic registering %q", name)
+				}
+			}()
+			RegisterBuiltin(name, func(common.Scope, ...interface{}) interface{} { return "" })
+		}()
+	}
+}
+
+func TestRegisterLiteral(t *testing.T) {
+	t.Cleanup(func() { delete(literals, "ARROW") })
+	RegisterLiteral("ARROW", "->")
+	out, err := GenerateOne(`s : "a" ARROW "b" DQ.`, 100, "", "", "s")
+	if err != nil {
+		t.Fatal(err)
+	} else if out != "a->b\"" {
+		t.Fatalf("unexpected output %q", out)
+	}
+	for _, term := range []string{"tab", "1TAB", "TA-B", ""} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatalf("expected panic registering %q", term)
+				}
+			}()
+			RegisterLiteral(term, "x")
+		}()
+	}
+}
+
+func TestLiterals(t *testing.T) {
+	text := `s : "name" TAB "age" SP "years" CR NL.`
+	out, err := GenerateOne(text, 100, "", "", "s")
+	if err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal([]byte(out), []byte("name\tage years\r\n")) {
+		t.Fatalf("unexpected output %q", out)
+	}
+}